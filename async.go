@@ -0,0 +1,330 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrAllRejected is returned by Any when every future has failed.
+// The individual errors are joined behind it and can be inspected with errors.Is/As.
+//
+// Any 中所有 Future 都失败时返回的错误
+var ErrAllRejected = errors.New("all futures rejected")
+
+// PanicError wraps a value recovered from a panicking goroutine, together with the stack at the time of the panic.
+//
+// 异步任务 panic 后被恢复时返回的错误，保存了 panic 的值和调用栈
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Future represents the result of an asynchronous computation, similar to a js Promise.
+// A Future is settled exactly once, either with a value or with an error.
+//
+// 类似js的Promise，表示一个异步计算的结果，只会被完成一次
+type Future[T any] struct {
+	done  chan struct{}
+	once  sync.Once
+	value T
+	err   error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+func (f *Future[T]) settle(value T, err error) {
+	f.once.Do(func() {
+		f.value = value
+		f.err = err
+		close(f.done)
+	})
+}
+
+// Async runs fn in a new goroutine and returns a Future for its result.
+// A panic inside fn is recovered and reported as a *PanicError.
+//
+// 在新的goroutine中执行fn，返回一个Future
+// 示例:
+//
+//	f := Async(func() (int, error) { return 1, nil })
+//	v, err := f.Await(ctx) // 返回: 1, nil
+func Async[T any](fn func() (T, error)) *Future[T] {
+	f := newFuture[T]()
+	go func() {
+		var (
+			value T
+			err   error
+		)
+		defer func() {
+			if r := recover(); r != nil {
+				f.settle(Empty[T](), &PanicError{Value: r, Stack: debug.Stack()})
+				return
+			}
+			f.settle(value, err)
+		}()
+		value, err = fn()
+	}()
+	return f
+}
+
+// Resolve returns a Future that is already settled with value.
+//
+// 返回一个已经成功完成的Future
+func Resolve[T any](value T) *Future[T] {
+	f := newFuture[T]()
+	f.settle(value, nil)
+	return f
+}
+
+// Reject returns a Future that is already settled with err.
+//
+// 返回一个已经失败的Future
+func Reject[T any](err error) *Future[T] {
+	f := newFuture[T]()
+	f.settle(Empty[T](), err)
+	return f
+}
+
+// Done returns a channel that is closed once the future is settled.
+//
+// 返回一个在Future完成后关闭的channel
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await blocks until the future is settled or ctx is done.
+// When ctx is done first, the zero value and ctx.Err() are returned; the underlying computation keeps running.
+//
+// 等待Future完成，ctx结束时提前返回ctx.Err()
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return Empty[T](), ctx.Err()
+	}
+}
+
+// Result blocks until the future is settled and returns its value and error.
+//
+// 阻塞等待Future完成
+func (f *Future[T]) Result() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Then returns a Future that runs fn with the value of f once f succeeds.
+// If f fails, the returned Future fails with the same error and fn is not called.
+//
+// 链式调用，f成功后用其结果执行fn
+// 示例:
+//
+//	f := Then(Resolve(2), func(v int) (string, error) { return strconv.Itoa(v * 2), nil })
+//	f.Result() // 返回: "4", nil
+func Then[T any, R any](f *Future[T], fn func(value T) (R, error)) *Future[R] {
+	return Async(func() (R, error) {
+		value, err := f.Result()
+		if err != nil {
+			return Empty[R](), err
+		}
+		return fn(value)
+	})
+}
+
+// Settled is the outcome of a single future as reported by AllSettled.
+//
+// AllSettled 中单个Future的结果
+type Settled[T any] struct {
+	Value T
+	Err   error
+}
+
+// All waits for all futures and returns their values in the same order.
+// It fails fast: the first error (or ctx being done) is returned immediately without waiting for the rest.
+//
+// 类似js的Promise.all，按顺序返回结果，遇到第一个错误立即返回
+func All[T any](ctx context.Context, futures ...*Future[T]) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	for _, f := range futures {
+		f := f
+		go func() {
+			select {
+			case <-f.done:
+				if f.err != nil {
+					select {
+					case errCh <- f.err:
+					default:
+					}
+				}
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	result := make([]T, len(futures))
+	for i, f := range futures {
+		select {
+		case <-f.done:
+			if f.err != nil {
+				return nil, f.err
+			}
+			result[i] = f.value
+		case err := <-errCh:
+			return nil, err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// AllCollect waits for all futures and returns their values in the same order.
+// Unlike All it does not fail fast: every future is awaited and all errors are joined with errors.Join.
+// Values of failed futures are left as zero values.
+//
+// 同All，但是会等待所有Future完成，并合并所有错误
+func AllCollect[T any](ctx context.Context, futures ...*Future[T]) ([]T, error) {
+	result := make([]T, len(futures))
+	var errs []error
+	for i, f := range futures {
+		value, err := f.Await(ctx)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return result, errors.Join(append(errs, ctxErr)...)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		result[i] = value
+	}
+	return result, errors.Join(errs...)
+}
+
+// AllSettled waits for all futures and reports the outcome of each one in the same order.
+// The returned error is only non-nil when ctx is done before every future has settled.
+//
+// 类似js的Promise.allSettled
+func AllSettled[T any](ctx context.Context, futures ...*Future[T]) ([]Settled[T], error) {
+	result := make([]Settled[T], len(futures))
+	for i, f := range futures {
+		select {
+		case <-f.done:
+			result[i] = Settled[T]{Value: f.value, Err: f.err}
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// Any returns the value of the first future that succeeds.
+// If every future fails, the returned error wraps ErrAllRejected and all individual errors.
+//
+// 类似js的Promise.any，返回第一个成功的结果
+func Any[T any](ctx context.Context, futures ...*Future[T]) (T, error) {
+	if len(futures) == 0 {
+		return Empty[T](), ErrAllRejected
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	settled := make(chan int, len(futures))
+	for i, f := range futures {
+		i, f := i, f
+		go func() {
+			select {
+			case <-f.done:
+				settled <- i
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	errs := make([]error, len(futures))
+	for range futures {
+		select {
+		case i := <-settled:
+			f := futures[i]
+			if f.err == nil {
+				return f.value, nil
+			}
+			errs[i] = f.err
+		case <-ctx.Done():
+			return Empty[T](), ctx.Err()
+		}
+	}
+	return Empty[T](), fmt.Errorf("%w: %w", ErrAllRejected, errors.Join(errs...))
+}
+
+// Race returns the value and error of the first future that settles, whether it succeeded or failed.
+// With no futures it blocks until ctx is done.
+//
+// 类似js的Promise.race，返回第一个完成的Future的结果
+func Race[T any](ctx context.Context, futures ...*Future[T]) (T, error) {
+	if len(futures) == 0 {
+		<-ctx.Done()
+		return Empty[T](), ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	first := make(chan *Future[T], 1)
+	for _, f := range futures {
+		f := f
+		go func() {
+			select {
+			case <-f.done:
+				select {
+				case first <- f:
+				default:
+				}
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	select {
+	case f := <-first:
+		return f.value, f.err
+	case <-ctx.Done():
+		return Empty[T](), ctx.Err()
+	}
+}
+
+// MapAsync runs iteratee for every element of collection concurrently and returns one Future per element, in order.
+// Combine it with All, AllSettled, Any or Race to collect the results.
+//
+// 并发地对切片每个元素执行iteratee，返回Future切片
+// 示例:
+//
+//	futures := MapAsync([]string{"a", "b"}, func(s string, _ int) (int, error) { return len(s), nil })
+//	All(ctx, futures...) // 返回: [1, 1], nil
+func MapAsync[T any, R any](collection []T, iteratee func(item T, index int) (R, error)) []*Future[R] {
+	return Map(collection, func(item T, index int) *Future[R] {
+		return Async(func() (R, error) {
+			return iteratee(item, index)
+		})
+	})
+}
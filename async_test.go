@@ -0,0 +1,153 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncAwait(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	v1, err1 := Async(func() (int, error) { return 42, nil }).Await(ctx)
+	is.Equal(42, v1)
+	is.NoError(err1)
+
+	errBoom := errors.New("boom")
+	_, err2 := Async(func() (int, error) { return 0, errBoom }).Await(ctx)
+	is.ErrorIs(err2, errBoom)
+
+	_, err3 := Async(func() (int, error) { panic("oops") }).Await(ctx)
+	var panicErr *PanicError
+	is.ErrorAs(err3, &panicErr)
+	is.Equal("oops", panicErr.Value)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err4 := Async(func() (int, error) {
+		time.Sleep(time.Second)
+		return 1, nil
+	}).Await(timeoutCtx)
+	is.ErrorIs(err4, context.DeadlineExceeded)
+}
+
+func TestThen(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	r1, err1 := Then(Resolve(2), func(v int) (string, error) {
+		return strconv.Itoa(v * 2), nil
+	}).Result()
+	is.Equal("4", r1)
+	is.NoError(err1)
+
+	errBoom := errors.New("boom")
+	called := false
+	_, err2 := Then(Reject[int](errBoom), func(v int) (string, error) {
+		called = true
+		return "", nil
+	}).Result()
+	is.ErrorIs(err2, errBoom)
+	is.False(called)
+}
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	r1, err1 := All(ctx, delayed(30*time.Millisecond, 1, nil), delayed(0, 2, nil), Resolve(3))
+	is.Equal([]int{1, 2, 3}, r1)
+	is.NoError(err1)
+
+	errBoom := errors.New("boom")
+	start := time.Now()
+	_, err2 := All(ctx, delayed(time.Second, 1, nil), delayed(0, 0, errBoom))
+	is.ErrorIs(err2, errBoom)
+	is.Less(time.Since(start), 500*time.Millisecond)
+
+	r3, err3 := All[int](ctx)
+	is.Equal([]int{}, r3)
+	is.NoError(err3)
+}
+
+func TestAllCollect(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	err1 := errors.New("err1")
+	err2 := errors.New("err2")
+	result, err := AllCollect(context.Background(), Reject[int](err1), Resolve(2), Reject[int](err2))
+	is.Equal([]int{0, 2, 0}, result)
+	is.ErrorIs(err, err1)
+	is.ErrorIs(err, err2)
+}
+
+func TestAllSettled(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	errBoom := errors.New("boom")
+	result, err := AllSettled(context.Background(), Resolve(1), Reject[int](errBoom))
+	is.NoError(err)
+	is.Equal([]Settled[int]{{Value: 1}, {Err: errBoom}}, result)
+}
+
+func TestAny(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	r1, err1 := Any(ctx, Reject[int](errBoom), delayed(10*time.Millisecond, 2, nil), delayed(time.Second, 3, nil))
+	is.Equal(2, r1)
+	is.NoError(err1)
+
+	err2 := errors.New("err2")
+	_, err := Any(ctx, Reject[int](errBoom), Reject[int](err2))
+	is.ErrorIs(err, ErrAllRejected)
+	is.ErrorIs(err, errBoom)
+	is.ErrorIs(err, err2)
+
+	_, err3 := Any[int](ctx)
+	is.ErrorIs(err3, ErrAllRejected)
+}
+
+func TestRace(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	r1, err1 := Race(ctx, delayed(time.Second, 1, nil), delayed(0, 2, nil))
+	is.Equal(2, r1)
+	is.NoError(err1)
+
+	errBoom := errors.New("boom")
+	_, err2 := Race(ctx, delayed(time.Second, 1, nil), delayed(0, 0, errBoom))
+	is.ErrorIs(err2, errBoom)
+}
+
+func TestMapAsync(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	futures := MapAsync([]string{"a", "bb", "ccc"}, func(s string, i int) (int, error) {
+		return len(s)*10 + i, nil
+	})
+	result, err := All(context.Background(), futures...)
+	is.NoError(err)
+	is.Equal([]int{10, 21, 32}, result)
+}
+
+func delayed[T any](d time.Duration, value T, err error) *Future[T] {
+	return Async(func() (T, error) {
+		time.Sleep(d)
+		return value, err
+	})
+}
@@ -0,0 +1,250 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned by Add and Flush after the batcher has been closed.
+//
+// Batcher关闭后调用Add/Flush返回的错误
+var ErrBatcherClosed = errors.New("batcher closed")
+
+// BatcherOptions configures a Batcher. At least one of MaxItems, MaxWeight or MaxLatency should be set,
+// otherwise items are only flushed by Flush and Close.
+//
+// Batcher的配置项
+type BatcherOptions[T any] struct {
+	// MaxItems flushes the batch once it holds this many items.
+	MaxItems int
+	// MaxWeight flushes the batch once the summed Weigher weight reaches it.
+	MaxWeight int
+	// Weigher returns the weight of an item, e.g. its size in bytes. Defaults to 1 per item.
+	Weigher func(item T) int
+	// MaxLatency flushes the batch when its oldest item has waited this long.
+	MaxLatency time.Duration
+	// Retries is the number of times a failed flush is retried before OnError is called.
+	Retries int
+	// RetryDelay is the pause between retries.
+	RetryDelay time.Duration
+	// OnError is called with the batch that could not be flushed after all retries.
+	// Errors of flushes triggered by Add, Flush or Close are also returned to the caller;
+	// errors of MaxLatency flushes are only reported here.
+	OnError func(batch []T, err error)
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// Batcher accumulates items and hands them to a flush function in batches, when the batch is full
+// by count or weight, when its oldest item is too old, or when Flush/Close is called.
+// It is the streaming counterpart of Chunk. Adds are safe for concurrent use; flushes never overlap.
+//
+// 批量累加器：按数量、权重或时间批量调用flush，是Chunk的流式版本
+type Batcher[T any] struct {
+	flush func(ctx context.Context, batch []T) error
+	opts  BatcherOptions[T]
+
+	mu     sync.Mutex
+	items  []T
+	weight int
+	closed bool
+	gen    uint64
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	// sendCond orders the flushes: the batch taken with generation n is sent when turn is n.
+	sendMu   sync.Mutex
+	sendCond *sync.Cond
+	turn     uint64
+}
+
+// NewBatcher creates a Batcher that passes accumulated items to flush.
+//
+// 创建Batcher
+// 示例:
+//
+//	b := NewBatcher(func(ctx context.Context, rows []Row) error { return db.InsertRows(ctx, rows) },
+//		BatcherOptions[Row]{MaxItems: 500, MaxLatency: 200 * time.Millisecond})
+//	defer b.Close(ctx)
+//	b.Add(ctx, row)
+func NewBatcher[T any](flush func(ctx context.Context, batch []T) error, opts BatcherOptions[T]) *Batcher[T] {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	if opts.Weigher == nil {
+		opts.Weigher = func(T) int { return 1 }
+	}
+	b := &Batcher[T]{
+		flush: flush,
+		opts:  opts,
+		stop:  make(chan struct{}),
+	}
+	b.sendCond = sync.NewCond(&b.sendMu)
+	return b
+}
+
+// Add appends items to the current batch, flushing it synchronously if it became full.
+// The returned error is the flush error, if a flush was triggered.
+// Batches are flushed in the order they were filled, also when Add is called concurrently.
+//
+// 添加元素，达到上限时同步flush
+func (b *Batcher[T]) Add(ctx context.Context, items ...T) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+
+	var (
+		full    [][]T
+		tickets []uint64
+	)
+	for _, item := range items {
+		if len(b.items) == 0 {
+			b.startTimer()
+		}
+		b.items = append(b.items, item)
+		b.weight += b.opts.Weigher(item)
+		if b.isFull() {
+			batch, ticket := b.take()
+			full = append(full, batch)
+			tickets = append(tickets, ticket)
+		}
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for i, batch := range full {
+		errs = append(errs, b.send(ctx, tickets[i], batch))
+	}
+	return errors.Join(errs...)
+}
+
+// Len returns the number of items waiting in the current batch.
+//
+// 返回当前批次中的元素数量
+func (b *Batcher[T]) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+// Flush flushes the current batch immediately, if it is not empty.
+//
+// 立即flush当前批次
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBatcherClosed
+	}
+	batch, ticket := b.take()
+	b.mu.Unlock()
+	return b.send(ctx, ticket, batch)
+}
+
+// Close stops the batcher and performs a final flush of the remaining items.
+// Further calls to Add and Flush return ErrBatcherClosed; Close itself may be called more than once.
+//
+// 关闭Batcher，并flush剩余的元素
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.stop)
+	batch, ticket := b.take()
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return b.send(ctx, ticket, batch)
+}
+
+func (b *Batcher[T]) isFull() bool {
+	if b.opts.MaxItems > 0 && len(b.items) >= b.opts.MaxItems {
+		return true
+	}
+	return b.opts.MaxWeight > 0 && b.weight >= b.opts.MaxWeight
+}
+
+// take detaches the current batch and returns it with the ticket to pass to send. b.mu must be held.
+func (b *Batcher[T]) take() ([]T, uint64) {
+	batch, ticket := b.items, b.gen
+	b.items = nil
+	b.weight = 0
+	b.gen++
+	return batch, ticket
+}
+
+// startTimer arms the max latency flush for the batch that is being started. b.mu must be held.
+func (b *Batcher[T]) startTimer() {
+	if b.opts.MaxLatency <= 0 {
+		return
+	}
+	gen := b.gen
+	after := b.opts.Clock.After(b.opts.MaxLatency)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		select {
+		case <-after:
+		case <-b.stop:
+			return
+		}
+
+		b.mu.Lock()
+		// 批次已经被其他途径flush
+		if b.gen != gen || b.closed {
+			b.mu.Unlock()
+			return
+		}
+		batch, ticket := b.take()
+		b.mu.Unlock()
+		_ = b.send(context.Background(), ticket, batch)
+	}()
+}
+
+// send flushes batch with retries once every batch taken before it has been sent.
+// Flushes are serialised so they never overlap, and every ticket returned by take must be sent.
+func (b *Batcher[T]) send(ctx context.Context, ticket uint64, batch []T) error {
+	b.sendMu.Lock()
+	// 批次在mu下取出，但在解锁后才发送，按取出顺序排队，避免并发Add时乱序
+	for b.turn != ticket {
+		b.sendCond.Wait()
+	}
+	defer func() {
+		b.turn++
+		b.sendCond.Broadcast()
+		b.sendMu.Unlock()
+	}()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	err := b.flush(ctx, batch)
+	for i := 0; err != nil && i < b.opts.Retries; i++ {
+		if b.opts.RetryDelay > 0 {
+			select {
+			case <-b.opts.Clock.After(b.opts.RetryDelay):
+			case <-ctx.Done():
+				return b.fail(batch, errors.Join(err, ctx.Err()))
+			}
+		}
+		err = b.flush(ctx, batch)
+	}
+	if err != nil {
+		return b.fail(batch, err)
+	}
+	return nil
+}
+
+func (b *Batcher[T]) fail(batch []T, err error) error {
+	if b.opts.OnError != nil {
+		b.opts.OnError(batch, err)
+	}
+	return err
+}
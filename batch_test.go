@@ -0,0 +1,156 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorder[T any] struct {
+	mu      sync.Mutex
+	batches [][]T
+}
+
+func (r *batchRecorder[T]) flush(_ context.Context, batch []T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *batchRecorder[T]) get() [][]T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]T{}, r.batches...)
+}
+
+func TestBatcherMaxItems(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	r := &batchRecorder[int]{}
+	b := NewBatcher(r.flush, BatcherOptions[int]{MaxItems: 2})
+
+	is.NoError(b.Add(ctx, 1, 2, 3))
+	is.Equal([][]int{{1, 2}}, r.get())
+	is.Equal(1, b.Len())
+
+	is.NoError(b.Add(ctx, 4, 5))
+	is.Equal([][]int{{1, 2}, {3, 4}}, r.get())
+
+	is.NoError(b.Close(ctx))
+	is.Equal([][]int{{1, 2}, {3, 4}, {5}}, r.get())
+	is.ErrorIs(b.Add(ctx, 6), ErrBatcherClosed)
+	is.ErrorIs(b.Flush(ctx), ErrBatcherClosed)
+	is.NoError(b.Close(ctx))
+}
+
+func TestBatcherMaxWeight(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	r := &batchRecorder[string]{}
+	b := NewBatcher(r.flush, BatcherOptions[string]{
+		MaxWeight: 5,
+		Weigher:   func(s string) int { return len(s) },
+	})
+
+	is.NoError(b.Add(ctx, "ab", "cd", "efg", "h"))
+	is.NoError(b.Flush(ctx))
+	is.NoError(b.Flush(ctx))
+	is.Equal([][]string{{"ab", "cd", "efg"}, {"h"}}, r.get())
+}
+
+func TestBatcherMaxLatency(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := &batchRecorder[int]{}
+	b := NewBatcher(r.flush, BatcherOptions[int]{MaxLatency: time.Second, Clock: clock})
+
+	is.NoError(b.Add(ctx, 1))
+	clock.Advance(500 * time.Millisecond)
+	is.NoError(b.Add(ctx, 2))
+	is.Empty(r.get())
+
+	clock.Advance(500 * time.Millisecond)
+	is.Eventually(func() bool { return len(r.get()) == 1 }, time.Second, time.Millisecond)
+	is.Equal([][]int{{1, 2}}, r.get())
+
+	is.NoError(b.Add(ctx, 3))
+	is.NoError(b.Close(ctx))
+	is.Equal([][]int{{1, 2}, {3}}, r.get())
+}
+
+func TestBatcherRetry(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	calls := 0
+	var failed []int
+	b := NewBatcher(func(_ context.Context, batch []int) error {
+		calls++
+		if calls < 3 {
+			return errBoom
+		}
+		return nil
+	}, BatcherOptions[int]{
+		Retries: 2,
+		OnError: func(batch []int, err error) { failed = append(failed, batch...) },
+	})
+
+	is.NoError(b.Add(ctx, 1))
+	is.NoError(b.Flush(ctx))
+	is.Equal(3, calls)
+	is.Empty(failed)
+
+	calls = -10
+	is.NoError(b.Add(ctx, 2))
+	is.ErrorIs(b.Close(ctx), errBoom)
+	is.Equal([]int{2}, failed)
+}
+
+func TestBatcherOrder(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	// Weigher在mu下调用，记录的就是批次被取出的顺序
+	var added []int
+	r := &batchRecorder[int]{}
+	b := NewBatcher(r.flush, BatcherOptions[int]{
+		MaxItems: 1,
+		Weigher: func(item int) int {
+			added = append(added, item)
+			return 1
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			is.NoError(b.Add(ctx, i))
+		}(i)
+	}
+	wg.Wait()
+	is.NoError(b.Close(ctx))
+
+	var flushed []int
+	for _, batch := range r.get() {
+		flushed = append(flushed, batch...)
+	}
+	is.Len(flushed, 50)
+	is.Equal(added, flushed)
+}
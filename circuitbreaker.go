@@ -0,0 +1,334 @@
+package goutils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned when the circuit breaker is open and rejects calls.
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrTooManyProbes is returned when the circuit breaker is half-open and all probe slots are taken.
+	ErrTooManyProbes = errors.New("circuit breaker is half-open: too many probes")
+)
+
+// CircuitState is the state of a CircuitBreaker.
+//
+// 熔断器状态
+type CircuitState int
+
+const (
+	// StateClosed lets every call through and records the outcomes.
+	StateClosed CircuitState = iota
+	// StateOpen rejects every call until the open timeout has elapsed.
+	StateOpen
+	// StateHalfOpen lets a limited number of probe calls through to decide whether to close again.
+	StateHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// circuitBuckets is the number of buckets of the rolling window.
+const circuitBuckets = 10
+
+// CircuitBreakerOptions configures a CircuitBreaker.
+// When neither ConsecutiveFailures nor FailureRatio is set, the breaker trips after 5 consecutive failures.
+//
+// 熔断器配置项
+type CircuitBreakerOptions struct {
+	// ConsecutiveFailures trips the breaker after this many failures in a row.
+	ConsecutiveFailures int
+	// FailureRatio trips the breaker when the ratio of failures in the rolling window reaches it (0 < ratio <= 1).
+	FailureRatio float64
+	// MinRequests is the number of calls needed in the rolling window before FailureRatio is evaluated.
+	MinRequests int
+	// Window is the length of the rolling window used by FailureRatio. Defaults to one minute.
+	Window time.Duration
+	// OpenTimeout is how long the breaker stays open before going half-open. Defaults to one minute.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probes allowed while half-open, and the number of
+	// successful probes needed to close the breaker. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure decides whether an error counts as a failure. Defaults to err != nil.
+	IsFailure func(err error) bool
+	// OnStateChange is called on every state transition. It is called with the breaker locked
+	// and must not call back into the breaker.
+	OnStateChange func(from, to CircuitState)
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// CircuitCounts holds the counters of the current rolling window.
+//
+// 熔断器当前窗口的统计
+type CircuitCounts struct {
+	Requests             int
+	Successes            int
+	Failures             int
+	ConsecutiveSuccesses int
+	ConsecutiveFailures  int
+}
+
+type circuitBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// CircuitBreaker stops calling a failing dependency for a while, giving it time to recover.
+//
+// 熔断器，在依赖持续失败时暂时拒绝调用
+type CircuitBreaker struct {
+	mu         sync.Mutex
+	opts       CircuitBreakerOptions
+	state      CircuitState
+	generation uint64
+	openedAt   time.Time
+	probes     int
+	// base is the creation time, from which the buckets of the rolling window are counted.
+	base    time.Time
+	buckets [circuitBuckets]circuitBucket
+	counts  CircuitCounts
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+//
+// 创建熔断器
+// 示例:
+//
+//	cb := NewCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 3, OpenTimeout: 30 * time.Second})
+//	user, err := Execute(cb, func() (User, error) { return client.GetUser(id) })
+func NewCircuitBreaker(opts CircuitBreakerOptions) *CircuitBreaker {
+	if opts.ConsecutiveFailures <= 0 && opts.FailureRatio <= 0 {
+		opts.ConsecutiveFailures = 5
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = time.Minute
+	}
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(err error) bool { return err != nil }
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &CircuitBreaker{opts: opts, base: opts.Clock.Now()}
+}
+
+// State returns the current state, moving from open to half-open if the open timeout has elapsed.
+//
+// 返回当前状态
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refresh(cb.opts.Clock.Now())
+	return cb.state
+}
+
+// Counts returns the counters of the current rolling window.
+//
+// 返回当前窗口的统计
+func (cb *CircuitBreaker) Counts() CircuitCounts {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.opts.Clock.Now()
+	cb.refresh(now)
+	counts := cb.counts
+	counts.Successes, counts.Failures = cb.windowCounts(now)
+	counts.Requests = counts.Successes + counts.Failures
+	return counts
+}
+
+// Reset forces the breaker back to the closed state and clears its counters.
+//
+// 重置为关闭状态
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.setState(StateClosed, cb.opts.Clock.Now())
+}
+
+// Allow asks the breaker for permission to make a call. On success it returns a done function
+// that must be called exactly once with the outcome of the call.
+// It returns ErrCircuitOpen or ErrTooManyProbes when the call is rejected.
+//
+// 两步式调用：先申请，调用结束后用done报告结果
+func (cb *CircuitBreaker) Allow() (done func(err error), err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.opts.Clock.Now()
+	cb.refresh(now)
+	switch cb.state {
+	case StateOpen:
+		return nil, ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.probes >= cb.opts.HalfOpenProbes {
+			return nil, ErrTooManyProbes
+		}
+		cb.probes++
+	}
+
+	generation := cb.generation
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			cb.done(generation, cb.opts.IsFailure(err))
+		})
+	}, nil
+}
+
+// Execute runs fn through the circuit breaker and returns its typed result.
+// When the breaker rejects the call, fn is not run and ErrCircuitOpen or ErrTooManyProbes is returned.
+// A panic inside fn is recorded as a failure and re-panicked.
+//
+// 通过熔断器执行fn
+func Execute[T any](cb *CircuitBreaker, fn func() (T, error)) (T, error) {
+	done, err := cb.Allow()
+	if err != nil {
+		return Empty[T](), err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			done(&PanicError{Value: r})
+			panic(r)
+		}
+	}()
+
+	result, err := fn()
+	done(err)
+	return result, err
+}
+
+func (cb *CircuitBreaker) done(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.opts.Clock.Now()
+	cb.refresh(now)
+	// 结果属于之前的状态周期，忽略
+	if generation != cb.generation {
+		return
+	}
+
+	if failed {
+		cb.counts.ConsecutiveFailures++
+		cb.counts.ConsecutiveSuccesses = 0
+	} else {
+		cb.counts.ConsecutiveSuccesses++
+		cb.counts.ConsecutiveFailures = 0
+	}
+
+	switch cb.state {
+	case StateClosed:
+		cb.record(now, failed)
+		if failed && cb.shouldTrip(now) {
+			cb.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		cb.probes--
+		if failed {
+			cb.setState(StateOpen, now)
+		} else if cb.counts.ConsecutiveSuccesses >= cb.opts.HalfOpenProbes {
+			cb.setState(StateClosed, now)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) shouldTrip(now time.Time) bool {
+	if cb.opts.ConsecutiveFailures > 0 && cb.counts.ConsecutiveFailures >= cb.opts.ConsecutiveFailures {
+		return true
+	}
+	if cb.opts.FailureRatio > 0 {
+		successes, failures := cb.windowCounts(now)
+		total := successes + failures
+		if total > 0 && total >= cb.opts.MinRequests && float64(failures)/float64(total) >= cb.opts.FailureRatio {
+			return true
+		}
+	}
+	return false
+}
+
+func (cb *CircuitBreaker) refresh(now time.Time) {
+	if cb.state == StateOpen && !now.Before(cb.openedAt.Add(cb.opts.OpenTimeout)) {
+		cb.setState(StateHalfOpen, now)
+	}
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState, now time.Time) {
+	prev := cb.state
+	cb.state = state
+	cb.generation++
+	cb.probes = 0
+	cb.counts = CircuitCounts{}
+	cb.buckets = [circuitBuckets]circuitBucket{}
+	if state == StateOpen {
+		cb.openedAt = now
+	}
+	if prev != state && cb.opts.OnStateChange != nil {
+		cb.opts.OnStateChange(prev, state)
+	}
+}
+
+func (cb *CircuitBreaker) bucketWidth() time.Duration {
+	return max(cb.opts.Window/circuitBuckets, 1)
+}
+
+// bucket returns the start of the bucket holding now and its index in cb.buckets.
+func (cb *CircuitBreaker) bucket(now time.Time) (time.Time, int) {
+	width := cb.bucketWidth()
+	// 从创建时间开始计数，时间早于1970年或被调回时也不会得到负的下标
+	n := now.Sub(cb.base) / width
+	if now.Before(cb.base.Add(n * width)) {
+		n--
+	}
+	i := int(n % circuitBuckets)
+	if i < 0 {
+		i += circuitBuckets
+	}
+	return cb.base.Add(n * width), i
+}
+
+func (cb *CircuitBreaker) record(now time.Time, failed bool) {
+	start, i := cb.bucket(now)
+	b := &cb.buckets[i]
+	if !b.start.Equal(start) {
+		*b = circuitBucket{start: start}
+	}
+	if failed {
+		b.failures++
+	} else {
+		b.successes++
+	}
+}
+
+func (cb *CircuitBreaker) windowCounts(now time.Time) (successes, failures int) {
+	start, _ := cb.bucket(now)
+	oldest := start.Add(-cb.opts.Window)
+	for _, b := range cb.buckets {
+		if b.start.After(oldest) {
+			successes += b.successes
+			failures += b.failures
+		}
+	}
+	return successes, failures
+}
@@ -0,0 +1,163 @@
+package goutils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var changes []string
+	cb := NewCircuitBreaker(CircuitBreakerOptions{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         10 * time.Second,
+		Clock:               clock,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	errBoom := errors.New("boom")
+	fail := func() (int, error) { return 0, errBoom }
+	succeed := func() (int, error) { return 1, nil }
+
+	_, err := Execute(cb, fail)
+	is.ErrorIs(err, errBoom)
+	v, err := Execute(cb, succeed)
+	is.Equal(1, v)
+	is.NoError(err)
+	_, _ = Execute(cb, fail)
+	is.Equal(StateClosed, cb.State())
+	_, _ = Execute(cb, fail)
+	is.Equal(StateOpen, cb.State())
+
+	called := false
+	_, err = Execute(cb, func() (int, error) {
+		called = true
+		return 0, nil
+	})
+	is.ErrorIs(err, ErrCircuitOpen)
+	is.False(called)
+
+	clock.Advance(10 * time.Second)
+	is.Equal(StateHalfOpen, cb.State())
+	_, _ = Execute(cb, fail)
+	is.Equal(StateOpen, cb.State())
+
+	clock.Advance(10 * time.Second)
+	v, err = Execute(cb, succeed)
+	is.Equal(1, v)
+	is.NoError(err)
+	is.Equal(StateClosed, cb.State())
+
+	is.Equal([]string{
+		"closed->open", "open->half-open", "half-open->open",
+		"open->half-open", "half-open->closed",
+	}, changes)
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	t.Parallel()
+
+	// 1970年之前和零值时间的FakeClock，以及创建后被调回的时钟
+	tests := []struct {
+		name       string
+		start, set time.Time
+	}{
+		{"2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"1960", time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		{"zero", time.Time{}, time.Time{}},
+		{"set back", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1960, 1, 1, 0, 0, 7, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := assert.New(t)
+			clock := NewFakeClock(tt.start)
+			cb := NewCircuitBreaker(CircuitBreakerOptions{
+				FailureRatio: 0.5,
+				MinRequests:  4,
+				Window:       10 * time.Second,
+				Clock:        clock,
+			})
+			if !tt.set.IsZero() {
+				clock.Set(tt.set)
+			}
+
+			errBoom := errors.New("boom")
+			for i := 0; i < 3; i++ {
+				done, err := cb.Allow()
+				is.NoError(err)
+				done(errBoom)
+			}
+			// 请求数不足MinRequests，不会熔断
+			is.Equal(StateClosed, cb.State())
+			is.Equal(CircuitCounts{Requests: 3, Failures: 3, ConsecutiveFailures: 3}, cb.Counts())
+
+			// 旧的失败移出窗口
+			clock.Advance(11 * time.Second)
+			is.Equal(0, cb.Counts().Requests)
+
+			for _, err := range []error{nil, nil, nil, errBoom, errBoom} {
+				done, allowErr := cb.Allow()
+				is.NoError(allowErr)
+				done(err)
+			}
+			is.Equal(StateClosed, cb.State())
+
+			done, _ := cb.Allow()
+			done(errBoom)
+			is.Equal(StateOpen, cb.State())
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cb := NewCircuitBreaker(CircuitBreakerOptions{
+		ConsecutiveFailures: 1,
+		HalfOpenProbes:      2,
+		OpenTimeout:         time.Second,
+		Clock:               clock,
+	})
+
+	done, _ := cb.Allow()
+	done(errors.New("boom"))
+	is.Equal(StateOpen, cb.State())
+
+	clock.Advance(time.Second)
+	done1, err1 := cb.Allow()
+	done2, err2 := cb.Allow()
+	_, err3 := cb.Allow()
+	is.NoError(err1)
+	is.NoError(err2)
+	is.ErrorIs(err3, ErrTooManyProbes)
+
+	done1(nil)
+	done1(errors.New("ignored"))
+	is.Equal(StateHalfOpen, cb.State())
+	done2(nil)
+	is.Equal(StateClosed, cb.State())
+
+	cb.Reset()
+	is.Equal(CircuitCounts{}, cb.Counts())
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	cb := NewCircuitBreaker(CircuitBreakerOptions{ConsecutiveFailures: 1})
+	is.Panics(func() {
+		_, _ = Execute(cb, func() (int, error) { panic("boom") })
+	})
+	is.Equal(StateOpen, cb.State())
+}
@@ -0,0 +1,35 @@
+package goutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	is.Equal(start, c.Now())
+
+	ch1 := c.After(time.Second)
+	ch2 := c.After(3 * time.Second)
+	is.Equal(2, c.Waiters())
+
+	c.Advance(2 * time.Second)
+	is.Equal(start.Add(2*time.Second), <-ch1)
+	select {
+	case <-ch2:
+		t.Fatal("ch2 fired too early")
+	default:
+	}
+
+	c.Set(start.Add(time.Hour))
+	is.Equal(start.Add(time.Hour), <-ch2)
+	is.Equal(0, c.Waiters())
+
+	is.Equal(start.Add(time.Hour), <-c.After(0))
+}
@@ -0,0 +1,598 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrArchiveTooLarge is returned when an archive being extracted exceeds ExtractOptions.MaxSize or MaxEntries.
+var ErrArchiveTooLarge = errors.New("archive exceeds the extraction limits")
+
+const (
+	defaultMaxExtractSize    = 4 * GiB
+	defaultMaxExtractEntries = 100_000
+)
+
+// ArchiveOptions configures Zip and Tar.
+//
+// 创建压缩包的配置项
+type ArchiveOptions struct {
+	// Include, if not empty, restricts the archive to the files matching one of these globs (see MatchGlob),
+	// relative to the source directory.
+	Include []string
+	// Exclude skips the files and directories matching one of these globs.
+	Exclude []string
+	// FollowSymlinks stores what symlinks point to instead of the symlinks themselves.
+	FollowSymlinks bool
+}
+
+// ExtractOptions configures Unzip and Untar. Whatever the options, entries are never written outside
+// of the destination directory: names with ".." climbing out of it, and symlinks or hard links pointing
+// outside of it, fail with ErrPathEscapes. Symlink targets may only use ".." at their start, as in "../a",
+// not after a name, as in "b/../a". Permissions are restricted to the rwx bits.
+//
+// 解压的配置项
+type ExtractOptions struct {
+	// Include, if not empty, only extracts the entries matching one of these globs (see MatchGlob).
+	Include []string
+	// Exclude skips the entries matching one of these globs, and everything below matching directories.
+	Exclude []string
+	// Overwrite is applied to existing files.
+	Overwrite OverwritePolicy
+	// MaxSize limits the total number of bytes extracted, 0 meaning 4 GiB and a negative value no limit.
+	MaxSize ByteSize
+	// MaxEntries limits the number of entries, 0 meaning 100000 and a negative value no limit.
+	MaxEntries int
+}
+
+// Zip creates the zip archive dst from src. If src is a directory, its content is stored,
+// with names relative to it; if it is a file, only that file is stored. Permissions, modification
+// times and symlinks are kept. dst is written atomically.
+//
+// 将文件或目录压缩为zip
+// 示例:
+//
+//	err := file.Zip(ctx, "dist", "release.zip", file.ArchiveOptions{Exclude: []string{"*.map"}})
+func Zip(ctx context.Context, src, dst string, opts ArchiveOptions) error {
+	return createArchive(ctx, src, dst, opts, func(w io.Writer) archiveWriter {
+		return &zipWriter{zw: zip.NewWriter(w)}
+	})
+}
+
+// Tar creates the tar archive dst from src like Zip. dst is gzip compressed if its name ends with
+// ".gz" or ".tgz".
+//
+// 将文件或目录打包为tar，目标以.gz或.tgz结尾时使用gzip压缩
+// 示例:
+//
+//	err := file.Tar(ctx, "data", "backup.tar.gz", file.ArchiveOptions{})
+func Tar(ctx context.Context, src, dst string, opts ArchiveOptions) error {
+	gzipped := strings.HasSuffix(dst, ".gz") || strings.HasSuffix(dst, ".tgz")
+	return createArchive(ctx, src, dst, opts, func(w io.Writer) archiveWriter {
+		tw := &tarWriter{}
+		if gzipped {
+			tw.gz = gzip.NewWriter(w)
+			w = tw.gz
+		}
+		tw.tw = tar.NewWriter(w)
+		return tw
+	})
+}
+
+// Unzip extracts the zip archive src into the directory dst, creating it if needed.
+// It is safe to use on untrusted archives, see ExtractOptions.
+//
+// 解压zip到目录，可安全地用于不可信的压缩包
+// 示例:
+//
+//	err := file.Unzip(ctx, "upload.zip", "out", file.ExtractOptions{MaxSize: 100 * file.MiB})
+func Unzip(ctx context.Context, src, dst string, opts ExtractOptions) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	x, err := newExtractor(ctx, dst, opts)
+	if err != nil {
+		return err
+	}
+	if x.maxEntries >= 0 && len(r.File) > x.maxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, x.maxEntries)
+	}
+	for _, f := range r.File {
+		e := archiveEntry{
+			// zip规定使用/分隔，但Windows上的一些工具会写入\
+			name:    strings.ReplaceAll(f.Name, `\`, "/"),
+			mode:    f.Mode(),
+			modTime: f.Modified,
+			open:    f.Open,
+		}
+		if err := x.extract(e); err != nil {
+			return err
+		}
+	}
+	return x.finish()
+}
+
+// Untar extracts the tar archive src into the directory dst like Unzip.
+// Gzip compressed archives are detected automatically.
+//
+// 解包tar到目录，自动识别gzip压缩
+func Untar(ctx context.Context, src, dst string, opts ExtractOptions) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return UntarReader(ctx, f, dst, opts)
+}
+
+// UntarReader extracts the tar stream r into the directory dst like Untar.
+//
+// 从io.Reader解包tar
+func UntarReader(ctx context.Context, r io.Reader, dst string, opts ExtractOptions) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	x, err := newExtractor(ctx, dst, opts)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		e := archiveEntry{
+			name:    hdr.Name,
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+			link:    hdr.Linkname,
+			open:    func() (io.ReadCloser, error) { return io.NopCloser(tr), nil },
+		}
+		switch hdr.Typeflag {
+		case tar.TypeLink:
+			e.hardLink = true
+		case tar.TypeSymlink:
+			e.mode |= fs.ModeSymlink
+		case tar.TypeReg, tar.TypeDir:
+		default:
+			// 设备文件、FIFO等不解压
+			continue
+		}
+		if err := x.extract(e); err != nil {
+			return err
+		}
+	}
+	return x.finish()
+}
+
+// archiveWriter adds entries to a zip or tar archive.
+type archiveWriter interface {
+	// add stores e, with the content of r for regular files and link as the target of symlinks.
+	add(e Entry, link string, r io.Reader) error
+	Close() error
+}
+
+func createArchive(ctx context.Context, src, dst string, opts ArchiveOptions, newWriter func(w io.Writer) archiveWriter) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	w, err := NewAtomicWriter(dst, 0o644, AtomicOptions{})
+	if err != nil {
+		return err
+	}
+	aw := newWriter(w)
+	// 目标位于源目录内时，不能把压缩包自身打包进去
+	tmpInfo, _ := os.Stat(w.tmp.Name())
+	oldInfo, _ := os.Stat(dst)
+
+	add := func(e Entry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if os.SameFile(e.FileInfo, tmpInfo) || (oldInfo != nil && os.SameFile(e.FileInfo, oldInfo)) {
+			return nil
+		}
+		switch {
+		case e.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(e.Path)
+			if err != nil {
+				return err
+			}
+			return aw.add(e, target, nil)
+		case e.Mode().IsRegular():
+			f, err := os.Open(e.Path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return aw.add(e, "", f)
+		case e.IsDir():
+			return aw.add(e, "", nil)
+		}
+		return nil
+	}
+
+	if info.IsDir() {
+		walkOpts := WalkOptions{FollowSymlinks: opts.FollowSymlinks}
+		if len(opts.Exclude) > 0 {
+			walkOpts.Prune = ByGlob(opts.Exclude...)
+			walkOpts.Filters = append(walkOpts.Filters, Not(ByGlob(opts.Exclude...)))
+		}
+		if len(opts.Include) > 0 {
+			walkOpts.Filters = append(walkOpts.Filters, Or(DirsOnly(), ByGlob(opts.Include...)))
+		}
+		err = Walk(src, walkOpts, add)
+	} else {
+		err = add(Entry{FileInfo: info, Path: src, Rel: info.Name(), Depth: 1})
+	}
+	if err == nil {
+		err = aw.Close()
+	}
+	if err != nil {
+		_ = w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipWriter) add(e Entry, link string, r io.Reader) error {
+	hdr, err := zip.FileInfoHeader(e.FileInfo)
+	if err != nil {
+		return err
+	}
+	hdr.Name = e.Rel
+	hdr.Modified = e.ModTime()
+	if e.IsDir() {
+		hdr.Name += "/"
+		hdr.Method = zip.Store
+	} else {
+		hdr.Method = zip.Deflate
+	}
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	if link != "" {
+		// zip将符号链接的目标保存为文件内容
+		_, err = io.WriteString(w, link)
+		return err
+	}
+	if r != nil {
+		_, err = io.Copy(w, r)
+	}
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
+
+type tarWriter struct {
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+func (t *tarWriter) add(e Entry, link string, r io.Reader) error {
+	hdr, err := tar.FileInfoHeader(e.FileInfo, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = e.Rel
+	if e.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if r != nil {
+		_, err = io.Copy(t.tw, r)
+	}
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	err := t.tw.Close()
+	if t.gz != nil {
+		if gzErr := t.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	return err
+}
+
+// archiveEntry is an entry of a zip or tar archive being extracted.
+type archiveEntry struct {
+	name    string
+	mode    fs.FileMode
+	modTime time.Time
+	// link is the target of symlinks and hard links; zip stores symlink targets as content instead.
+	link     string
+	hardLink bool
+	open     func() (io.ReadCloser, error)
+}
+
+// extractor writes archive entries below dst, enforcing the limits of ExtractOptions.
+type extractor struct {
+	ctx        context.Context
+	dst        string
+	opts       ExtractOptions
+	copier     *copier
+	maxSize    int64
+	maxEntries int
+	size       int64
+	entries    int
+	dirs       []archiveEntry
+}
+
+func newExtractor(ctx context.Context, dst string, opts ExtractOptions) (*extractor, error) {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return nil, err
+	}
+	x := &extractor{
+		ctx:        ctx,
+		dst:        dst,
+		opts:       opts,
+		copier:     newCopier(ctx, CopyOptions{Overwrite: opts.Overwrite}),
+		maxSize:    int64(opts.MaxSize),
+		maxEntries: opts.MaxEntries,
+	}
+	if x.maxSize == 0 {
+		x.maxSize = int64(defaultMaxExtractSize)
+	}
+	if x.maxEntries == 0 {
+		x.maxEntries = defaultMaxExtractEntries
+	}
+	return x, nil
+}
+
+// skip reports whether the entry rel is filtered out by Include and Exclude.
+func (x *extractor) skip(rel string, isDir bool) (bool, error) {
+	for p := rel; p != "."; p = path.Dir(p) {
+		if ok, err := MatchAnyGlob(x.opts.Exclude, p); err != nil || ok {
+			return true, err
+		}
+	}
+	if len(x.opts.Include) == 0 || isDir {
+		return false, nil
+	}
+	ok, err := MatchAnyGlob(x.opts.Include, rel)
+	return !ok, err
+}
+
+func (x *extractor) extract(e archiveEntry) error {
+	if err := x.ctx.Err(); err != nil {
+		return err
+	}
+	rel := strings.TrimPrefix(path.Clean("/"+e.name), "/")
+	if rel == "" {
+		return nil
+	}
+	target, err := SecureJoin(x.dst, e.name)
+	if err != nil {
+		return err
+	}
+	if skip, err := x.skip(rel, e.mode.IsDir()); err != nil || skip {
+		return err
+	}
+	x.entries++
+	if x.maxEntries >= 0 && x.entries > x.maxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveTooLarge, x.maxEntries)
+	}
+
+	if e.mode.IsDir() {
+		if err := os.MkdirAll(target, e.mode.Perm()|0o700); err != nil {
+			return err
+		}
+		e.name = target
+		x.dirs = append(x.dirs, e)
+		return nil
+	}
+
+	info := archiveInfo{e}
+	if ok, err := x.copier.shouldWrite(info, target); err != nil || !ok {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	// 不能通过已存在的符号链接写入
+	if old, err := os.Lstat(target); err == nil && !old.Mode().IsRegular() {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case e.hardLink:
+		linkTarget, err := SecureJoin(x.dst, e.link)
+		if err != nil {
+			return err
+		}
+		// os.Link不跟随符号链接，硬链接到相对符号链接时，需要在新位置重新检查其目标
+		if info, err := os.Lstat(linkTarget); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(linkTarget)
+			if err != nil {
+				return err
+			}
+			if err := x.checkSymlink(link, target); err != nil {
+				return err
+			}
+		}
+		_ = os.Remove(target)
+		return os.Link(linkTarget, target)
+	case e.mode&fs.ModeSymlink != 0:
+		return x.symlink(e, target)
+	case e.mode.IsRegular():
+		return x.writeFile(e, target)
+	}
+	return nil
+}
+
+// symlink creates a symlink, refusing targets outside of dst.
+func (x *extractor) symlink(e archiveEntry, target string) error {
+	link := e.link
+	if link == "" {
+		r, err := e.open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(io.LimitReader(r, 4096))
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+		link = string(data)
+	}
+	if err := x.checkSymlink(link, target); err != nil {
+		return err
+	}
+	_ = os.Remove(target)
+	return os.Symlink(link, target)
+}
+
+// checkSymlink fails with ErrPathEscapes if a symlink to link created at target would point outside of dst,
+// following the symlinks already on disk. The parent directory of target must exist.
+func (x *extractor) checkSymlink(link, target string) error {
+	escapes := &os.LinkError{Op: "symlink", Old: link, New: target, Err: ErrPathEscapes}
+	if filepath.IsAbs(link) || path.IsAbs(filepath.ToSlash(link)) {
+		return escapes
+	}
+	// ..只允许出现在开头：系统从前面符号链接的实际位置解析..，而该链接可能在这之前或之后才解压出来
+	parts := strings.Split(filepath.ToSlash(link), "/")
+	up := 0
+	for up < len(parts) && parts[up] == ".." {
+		up++
+	}
+	if slices.Contains(parts[up:], "..") {
+		return escapes
+	}
+
+	realDst, err := filepath.EvalSymlinks(x.dst)
+	if err != nil {
+		return err
+	}
+	// 开头的..从链接所在目录的实际位置算起
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	for i := 0; i < up; i++ {
+		dir = filepath.Dir(dir)
+	}
+	real, err := evalExisting(filepath.Join(dir, filepath.FromSlash(path.Join(parts[up:]...))), 0)
+	if err != nil {
+		return err
+	}
+	if !isWithin(realDst, dir) || !isWithin(realDst, real) {
+		return escapes
+	}
+	return nil
+}
+
+// writeFile writes a regular file, counting its size against MaxSize.
+func (x *extractor) writeFile(e archiveEntry, target string) error {
+	r, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, e.mode.Perm())
+	if err != nil {
+		return err
+	}
+	var src io.Reader = r
+	if x.maxSize >= 0 {
+		// 按实际解压出的字节计数，压缩包中声明的大小不可信
+		src = io.LimitReader(r, x.maxSize-x.size+1)
+	}
+	n, err := io.CopyBuffer(out, &ctxReader{ctx: x.ctx, r: src}, x.copier.buf)
+	x.size += n
+	if err == nil && x.maxSize >= 0 && x.size > x.maxSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrArchiveTooLarge, x.maxSize)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(target)
+		return err
+	}
+	if err := os.Chmod(target, e.mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, e.modTime, e.modTime)
+}
+
+// finish applies the permissions and times of directories, deepest first, after their content is written.
+func (x *extractor) finish() error {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		d := x.dirs[i]
+		if err := os.Chmod(d.name, d.mode.Perm()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(d.name, d.modTime, d.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ctxReader stops reading when the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// archiveInfo adapts an archiveEntry to fs.FileInfo for the overwrite policy.
+type archiveInfo struct {
+	e archiveEntry
+}
+
+func (a archiveInfo) Name() string       { return path.Base(a.e.name) }
+func (a archiveInfo) Size() int64        { return 0 }
+func (a archiveInfo) Mode() fs.FileMode  { return a.e.mode }
+func (a archiveInfo) ModTime() time.Time { return a.e.modTime }
+func (a archiveInfo) IsDir() bool        { return a.e.mode.IsDir() }
+func (a archiveInfo) Sys() any           { return nil }
@@ -0,0 +1,290 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listTree returns the slash separated paths of the files below root.
+func listTree(t *testing.T, root string) []string {
+	t.Helper()
+	var names []string
+	err := Walk(root, WalkOptions{}, func(e Entry) error {
+		names = append(names, e.Rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"out.zip", "out.tar", "out.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			src := makeTree(t, map[string]string{
+				"a.txt":       "hello",
+				"bin/run.sh":  "#!/bin/sh",
+				"sub/b.txt":   "world",
+				"skip/c.log":  "log",
+				"sub/d.log":   "log",
+				"empty/":      "",
+				"sub/deep/e":  "e",
+				"sub/deep/f~": "backup",
+			})
+			old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := os.Chtimes(filepath.Join(src, "a.txt"), old, old); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(filepath.Join(src, "bin", "run.sh"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if runtime.GOOS != "windows" {
+				if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			archive := filepath.Join(t.TempDir(), name)
+			create, extract := Zip, Unzip
+			if strings.Contains(name, ".tar") {
+				create, extract = Tar, Untar
+			}
+			opts := ArchiveOptions{Exclude: []string{"skip", "*~", "*.log"}}
+			if err := create(ctx, src, archive, opts); err != nil {
+				t.Fatalf("create error = %v", err)
+			}
+
+			dst := filepath.Join(t.TempDir(), "out")
+			if err := extract(ctx, archive, dst, ExtractOptions{}); err != nil {
+				t.Fatalf("extract error = %v", err)
+			}
+			want := []string{"a.txt", "bin", "bin/run.sh", "empty", "sub", "sub/b.txt", "sub/deep", "sub/deep/e"}
+			if runtime.GOOS != "windows" {
+				want = append(want, "link")
+				sort.Strings(want)
+			}
+			if got := listTree(t, dst); strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("extracted %v, want %v", got, want)
+			}
+			assertFileContent(t, filepath.Join(dst, "sub", "b.txt"), "world")
+			if info, _ := os.Stat(filepath.Join(dst, "a.txt")); !info.ModTime().Equal(old) {
+				t.Errorf("mtime = %v, want %v", info.ModTime(), old)
+			}
+			if runtime.GOOS != "windows" {
+				if info, _ := os.Stat(filepath.Join(dst, "bin", "run.sh")); info.Mode().Perm() != 0o755 {
+					t.Errorf("mode = %v, want 0755", info.Mode())
+				}
+				if target, err := os.Readlink(filepath.Join(dst, "link")); err != nil || target != "a.txt" {
+					t.Errorf("Readlink() = %q, %v", target, err)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveFilters(t *testing.T) {
+	ctx := context.Background()
+	src := makeTree(t, map[string]string{"a.go": "a", "b.txt": "b", "sub/c.go": "c"})
+	archive := filepath.Join(src, "self.zip")
+	// 压缩包位于源目录内时不能包含自身
+	if err := Zip(ctx, src, archive, ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Zip(ctx, src, archive, ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	if err := Unzip(ctx, archive, dst, ExtractOptions{Include: []string{"*.go"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(listTree(t, dst), ","); got != "a.go,sub,sub/c.go" {
+		t.Errorf("extracted %s", got)
+	}
+
+	single := filepath.Join(t.TempDir(), "single.tgz")
+	if err := Tar(ctx, filepath.Join(src, "b.txt"), single, ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dst = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dst, "b.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Untar(ctx, single, dst, ExtractOptions{Overwrite: OverwriteError}); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Untar(OverwriteError) error = %v", err)
+	}
+	if err := Untar(ctx, single, dst, ExtractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, filepath.Join(dst, "b.txt"), "b")
+}
+
+// writeZip creates a zip archive with the given entries, names ending in "/" being directories.
+func writeZip(t *testing.T, entries map[string]string, links map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	for name, target := range links {
+		hdr := &zip.FileHeader{Name: name}
+		hdr.SetMode(os.ModeSymlink | 0o777)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(target))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "test.zip")
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"../evil.txt", "a/../../evil.txt", `..\evil.txt`} {
+		root := t.TempDir()
+		dst := filepath.Join(root, "dst")
+		archive := writeZip(t, map[string]string{"ok.txt": "ok", name: "pwned"}, nil)
+		if err := Unzip(ctx, archive, dst, ExtractOptions{}); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("Unzip(%q) error = %v, want ErrPathEscapes", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "evil.txt")); err == nil {
+			t.Errorf("Unzip(%q) wrote outside of the destination", name)
+		}
+	}
+
+	// 绝对路径被视为相对于目标目录
+	dst := t.TempDir()
+	if err := Unzip(ctx, writeZip(t, map[string]string{"/abs.txt": "x"}, nil), dst, ExtractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, filepath.Join(dst, "abs.txt"), "x")
+}
+
+func TestExtractSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	ctx := context.Background()
+	for _, target := range []string{"../outside", "/etc/passwd", "sub/../../x"} {
+		archive := writeZip(t, nil, map[string]string{"link": target})
+		if err := Unzip(ctx, archive, t.TempDir(), ExtractOptions{}); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("Unzip(link -> %q) error = %v, want ErrPathEscapes", target, err)
+		}
+	}
+
+	// 先解压指向外部的符号链接，再通过它写文件
+	outside := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "dir", Typeflag: tar.TypeSymlink, Linkname: outside})
+	tw.WriteHeader(&tar.Header{Name: "dir/evil", Typeflag: tar.TypeReg, Size: 1, Mode: 0o644})
+	tw.Write([]byte("x"))
+	tw.WriteHeader(&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "../outside"})
+	tw.Close()
+	if err := UntarReader(ctx, &buf, t.TempDir(), ExtractOptions{}); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("UntarReader() error = %v, want ErrPathEscapes", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("UntarReader() wrote outside of the destination: %v", entries)
+	}
+
+	// 硬链接到相对符号链接时，按硬链接的位置检查符号链接的目标
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a/b/link", Typeflag: tar.TypeSymlink, Linkname: "../../x"})
+	tw.WriteHeader(&tar.Header{Name: "a/b/same", Typeflag: tar.TypeLink, Linkname: "a/b/link"})
+	tw.WriteHeader(&tar.Header{Name: "top", Typeflag: tar.TypeLink, Linkname: "a/b/link"})
+	tw.Close()
+	dst := t.TempDir()
+	if err := UntarReader(ctx, &buf, dst, ExtractOptions{}); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("UntarReader(hard link to symlink) error = %v, want ErrPathEscapes", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "a", "b", "same")); err != nil {
+		t.Errorf("hard link to a symlink at the same depth: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "top")); !os.IsNotExist(err) {
+		t.Errorf("escaping hard link was created: %v", err)
+	}
+
+	// 链式符号链接：b -> .，再通过b/..逃出目标目录，两种解压顺序都要拒绝
+	for _, names := range [][]string{{"b", "a"}, {"a", "b"}} {
+		links := map[string]string{"b": ".", "a": "b/../secret"}
+		buf.Reset()
+		tw = tar.NewWriter(&buf)
+		for _, name := range names {
+			tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: links[name]})
+		}
+		tw.Close()
+		dst = filepath.Join(t.TempDir(), "dst")
+		if err := UntarReader(ctx, &buf, dst, ExtractOptions{}); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("UntarReader(%v) error = %v, want ErrPathEscapes", names, err)
+		}
+		if _, err := os.Lstat(filepath.Join(dst, "a")); !os.IsNotExist(err) {
+			t.Errorf("UntarReader(%v) created the escaping link: %v", names, err)
+		}
+	}
+
+	// 指向内部的符号链接是允许的
+	archive := writeZip(t, map[string]string{"sub/a.txt": "a"}, map[string]string{"sub/link": "../sub/a.txt"})
+	dst = t.TempDir()
+	if err := Unzip(ctx, archive, dst, ExtractOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, filepath.Join(dst, "sub", "link"), "a")
+}
+
+func TestExtractLimits(t *testing.T) {
+	ctx := context.Background()
+	bomb := writeZip(t, map[string]string{"zeros": strings.Repeat("0", 1<<20)}, nil)
+	dst := t.TempDir()
+	err := Unzip(ctx, bomb, dst, ExtractOptions{MaxSize: 64 * KiB})
+	if !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("Unzip(MaxSize) error = %v, want ErrArchiveTooLarge", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "zeros")); err == nil {
+		t.Error("Unzip(MaxSize) kept the partial file")
+	}
+	if err := Unzip(ctx, bomb, t.TempDir(), ExtractOptions{MaxSize: -1}); err != nil {
+		t.Errorf("Unzip(unlimited) error = %v", err)
+	}
+
+	many := writeZip(t, map[string]string{"a": "", "b": "", "c": ""}, nil)
+	if err := Unzip(ctx, many, t.TempDir(), ExtractOptions{MaxEntries: 2}); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("Unzip(MaxEntries) error = %v, want ErrArchiveTooLarge", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"a", "b", "c"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644})
+	}
+	tw.Close()
+	if err := UntarReader(ctx, &buf, t.TempDir(), ExtractOptions{MaxEntries: 2}); !errors.Is(err, ErrArchiveTooLarge) {
+		t.Errorf("UntarReader(MaxEntries) error = %v, want ErrArchiveTooLarge", err)
+	}
+}
@@ -0,0 +1,241 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrWriterClosed is returned when writing to an AtomicWriter that was already committed or aborted.
+var ErrWriterClosed = errors.New("atomic writer already closed")
+
+// AtomicOptions configures an AtomicWriter.
+//
+// 原子写入的配置项
+type AtomicOptions struct {
+	// BackupSuffix, if set, keeps the previous version of the file at path+BackupSuffix (e.g. ".bak").
+	BackupSuffix string
+}
+
+// AtomicWriter writes a file atomically: data goes to a temporary file in the same directory,
+// which is fsynced and renamed over the target on Close, so readers see either the old or the
+// new content, never a partial file, even after a crash.
+//
+// If the target already exists, its permissions and (where supported) ownership are kept;
+// otherwise the perm given to NewAtomicWriter is used as is, without applying the umask.
+// If the target is a symlink, the file it points to is replaced.
+//
+// 原子写入文件：先写同目录下的临时文件，fsync后重命名覆盖目标文件
+type AtomicWriter struct {
+	path string
+	perm os.FileMode
+	opts AtomicOptions
+	tmp  *os.File
+	done bool
+}
+
+// NewAtomicWriter creates the temporary file for an atomic write of path.
+// Call Close to commit the new content or Abort to discard it.
+//
+// 创建原子写入器，Close提交，Abort放弃
+func NewAtomicWriter(path string, perm os.FileMode, opts AtomicOptions) (*AtomicWriter, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicWriter{path: path, perm: perm, opts: opts, tmp: tmp}, nil
+}
+
+// Write writes to the temporary file.
+func (w *AtomicWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, ErrWriterClosed
+	}
+	return w.tmp.Write(p)
+}
+
+// ReadFrom copies r into the temporary file.
+func (w *AtomicWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.done {
+		return 0, ErrWriterClosed
+	}
+	return io.Copy(w.tmp, r)
+}
+
+// Close commits the write: it fsyncs the temporary file, applies permissions, backs up the
+// previous version if configured, renames the temporary file over the target and fsyncs the directory.
+// On failure the temporary file is removed and the target is left untouched.
+// Calling Close after Close or Abort does nothing.
+//
+// 提交写入
+func (w *AtomicWriter) Close() (err error) {
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	tmpName := w.tmp.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if err := w.tmp.Sync(); err != nil {
+		_ = w.tmp.Close()
+		return err
+	}
+	if err := w.tmp.Close(); err != nil {
+		return err
+	}
+
+	perm := w.perm
+	old, statErr := os.Stat(w.path)
+	if statErr == nil {
+		perm = old.Mode().Perm()
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if statErr == nil {
+		copyOwner(tmpName, old)
+		if w.opts.BackupSuffix != "" {
+			if err := backup(w.path, w.path+w.opts.BackupSuffix); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Rename(tmpName, w.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(w.path))
+}
+
+// Abort discards the temporary file. Calling Abort after Close or Abort does nothing.
+//
+// 放弃写入，删除临时文件
+func (w *AtomicWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	_ = w.tmp.Close()
+	return os.Remove(w.tmp.Name())
+}
+
+// WriteAtomic atomically replaces the content of path with data. See AtomicWriter.
+//
+// 原子写入文件
+// 示例:
+//
+//	err := file.WriteAtomic("config.json", data, 0o644)
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	return WriteAtomicFrom(path, bytes.NewReader(data), perm)
+}
+
+// WriteAtomicFrom atomically replaces the content of path with everything read from r. See AtomicWriter.
+//
+// 从io.Reader流式地原子写入文件
+func WriteAtomicFrom(path string, r io.Reader, perm os.FileMode) error {
+	w, err := NewAtomicWriter(path, perm, AtomicOptions{})
+	if err != nil {
+		return err
+	}
+	if _, err := w.ReadFrom(r); err != nil {
+		_ = w.Abort()
+		return err
+	}
+	return w.Close()
+}
+
+// WriteAtomicFS atomically replaces the content of the file name of fsys with data: it is written to a
+// temporary file in the same directory, which is then renamed over name. On a DirFS this is WriteAtomic,
+// including the fsyncs; other filesystems only get what their Rename guarantees.
+//
+// 在文件系统中原子写入文件
+func WriteAtomicFS(fsys FS, name string, data []byte, perm fs.FileMode) error {
+	if d, ok := fsys.(*dirFS); ok {
+		p, err := d.resolve("write", name, true)
+		if err != nil {
+			return err
+		}
+		return WriteAtomic(p, data, perm)
+	}
+
+	dir, base := path.Split(name)
+	var (
+		tmp     string
+		f       File
+		openErr error
+	)
+	for i := 0; i < 100; i++ {
+		tmp = dir + "." + base + ".tmp-" + strconv.FormatUint(uint64(rand.Int63()), 36)
+		f, openErr = fsys.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(openErr, fs.ErrExist) {
+			break
+		}
+	}
+	if openErr != nil {
+		return openErr
+	}
+
+	_, err := f.Write(data)
+	if s, ok := f.(interface{ Sync() error }); ok && err == nil {
+		err = s.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if old, statErr := fsys.Stat(name); statErr == nil {
+			perm = old.Mode().Perm()
+		}
+		err = fsys.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = fsys.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = fsys.Remove(tmp)
+	}
+	return err
+}
+
+// backup keeps the current content of path at dst, preferring a hard link over a copy.
+func backup(path, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Link(path, dst); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,93 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	if err := WriteAtomic(path, []byte("v1"), 0o640); err != nil {
+		t.Fatalf("WriteAtomic() error = %v", err)
+	}
+	assertFileContent(t, path, "v1")
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+			t.Errorf("WriteAtomic() perm = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+		}
+	}
+
+	// 已存在的文件保留原来的权限
+	if err := WriteAtomicFrom(path, strings.NewReader("v2"), 0o600); err != nil {
+		t.Fatalf("WriteAtomicFrom() error = %v", err)
+	}
+	assertFileContent(t, path, "v2")
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+			t.Errorf("WriteAtomicFrom() perm = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestAtomicWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewAtomicWriter(path, 0o644, AtomicOptions{BackupSuffix: ".bak"})
+	if err != nil {
+		t.Fatalf("NewAtomicWriter() error = %v", err)
+	}
+	if _, err := w.Write([]byte("new")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// 提交前目标文件不变
+	assertFileContent(t, path, "old")
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	assertFileContent(t, path, "new")
+	assertFileContent(t, path+".bak", "old")
+
+	if _, err := w.Write([]byte("x")); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("Write() after Close error = %v, want %v", err, ErrWriterClosed)
+	}
+
+	w, err = NewAtomicWriter(path, 0o644, AtomicOptions{})
+	if err != nil {
+		t.Fatalf("NewAtomicWriter() error = %v", err)
+	}
+	_, _ = w.Write([]byte("discarded"))
+	if err := w.Abort(); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	assertFileContent(t, path, "new")
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func assertFileContent(t *testing.T, path string, want string) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) error = %v", path, err)
+	}
+	if string(got) != want {
+		t.Errorf("content of %q = %q, want %q", path, got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidByteSize is returned by ParseBytes for malformed sizes.
+var ErrInvalidByteSize = errors.New("invalid byte size")
+
+// ByteSize is a number of bytes. It prints in human readable form and can be used in JSON and YAML
+// configs, where it accepts both plain numbers and strings such as "512MiB" or "1.5 GB".
+//
+// 字节大小，可读地打印，并支持在JSON和YAML配置中使用"1.5GiB"这样的写法
+// 示例:
+//
+//	type Config struct {
+//		MaxUpload file.ByteSize `json:"maxUpload" yaml:"maxUpload"` // "maxUpload": "10MiB"
+//	}
+type ByteSize int64
+
+// Units of ByteSize, IEC (powers of 1024) and SI (powers of 1000).
+const (
+	B   ByteSize = 1
+	KiB ByteSize = 1 << (10 * iota)
+	MiB
+	GiB
+	TiB
+	PiB
+	EiB
+)
+
+const (
+	KB ByteSize = 1000
+	MB          = KB * 1000
+	GB          = MB * 1000
+	TB          = GB * 1000
+	PB          = TB * 1000
+	EB          = PB * 1000
+)
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// byteUnits maps the lower case unit names accepted by ParseBytes to their size.
+// Single letters such as "M" are IEC, like in ls, du and docker.
+var byteUnits = map[string]ByteSize{
+	"": B, "b": B, "byte": B, "bytes": B,
+	"k": KiB, "kib": KiB, "ki": KiB, "kb": KB,
+	"m": MiB, "mib": MiB, "mi": MiB, "mb": MB,
+	"g": GiB, "gib": GiB, "gi": GiB, "gb": GB,
+	"t": TiB, "tib": TiB, "ti": TiB, "tb": TB,
+	"p": PiB, "pib": PiB, "pi": PiB, "pb": PB,
+	"e": EiB, "eib": EiB, "ei": EiB, "eb": EB,
+}
+
+// ByteFormat configures FormatBytesWithOptions.
+//
+// 字节大小格式化的配置项
+type ByteFormat struct {
+	// SI uses powers of 1000 (kB, MB, ...) instead of powers of 1024 (KiB, MiB, ...).
+	SI bool
+	// Precision is the number of decimals, 0 prints whole units. Plain bytes never have decimals.
+	Precision int
+	// Compact omits the space between the number and the unit, e.g. "1.5GiB".
+	Compact bool
+}
+
+// FormatBytes formats n bytes with IEC units and one decimal, e.g. "1.5 GiB" or "512 B".
+//
+// 将字节数格式化为可读的形式
+// 示例:
+//
+//	file.FormatBytes(1536) // "1.5 KiB"
+func FormatBytes(n int64) string {
+	return FormatBytesWithOptions(n, ByteFormat{Precision: 1})
+}
+
+// FormatBytesWithOptions formats n bytes with the largest unit that keeps the number at least 1.
+//
+// 按配置格式化字节数
+// 示例:
+//
+//	file.FormatBytesWithOptions(1_500_000, file.ByteFormat{SI: true, Precision: 2}) // "1.50 MB"
+func FormatBytesWithOptions(n int64, opts ByteFormat) string {
+	base, units := 1024.0, iecUnits
+	if opts.SI {
+		base, units = 1000, siUnits
+	}
+	sep := " "
+	if opts.Compact {
+		sep = ""
+	}
+
+	value := math.Abs(float64(n))
+	exp := 0
+	for value >= base && exp < len(units)-1 {
+		value /= base
+		exp++
+	}
+	if exp == 0 {
+		return strconv.FormatInt(n, 10) + sep + units[0]
+	}
+	// 四舍五入可能进位到下一个单位，如1023.96 KiB以一位小数显示为1024.0 KiB
+	prec := max(opts.Precision, 0)
+	if s := strconv.FormatFloat(value, 'f', prec, 64); s == strconv.FormatFloat(base, 'f', prec, 64) && exp < len(units)-1 {
+		value /= base
+		exp++
+	}
+	if n < 0 {
+		value = -value
+	}
+	return strconv.FormatFloat(value, 'f', prec, 64) + sep + units[exp]
+}
+
+// ParseBytes parses a size such as "1.5GiB", "10 MB", "512k" or "4096". Units are case-insensitive:
+// KiB, MiB, GiB, TiB, PiB and EiB (also Ki, Mi, ... and K, M, ...) are powers of 1024,
+// kB, MB, GB, TB, PB and EB are powers of 1000. Fractions of a byte are rounded down.
+//
+// 解析字节大小字符串
+// 示例:
+//
+//	size, err := file.ParseBytes("1.5GiB") // 1610612736
+func ParseBytes(s string) (ByteSize, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '_'
+	})
+	if i < 0 {
+		i = len(str)
+	}
+	num, unit := str[:i], strings.ToLower(strings.TrimSpace(str[i:]))
+
+	mult, ok := byteUnits[unit]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	num = strings.ReplaceAll(num, "_", "")
+	if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+		if n > math.MaxInt64/int64(mult) {
+			return 0, fmt.Errorf("%w: %q overflows", ErrInvalidByteSize, s)
+		}
+		return ByteSize(n) * mult, nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	f *= float64(mult)
+	if f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q overflows", ErrInvalidByteSize, s)
+	}
+	return ByteSize(f), nil
+}
+
+// String formats b like FormatBytes.
+func (b ByteSize) String() string {
+	return FormatBytes(int64(b))
+}
+
+// MarshalText formats b exactly, with the IEC or SI unit that gives the shortest text with at most
+// three decimals, e.g. "1.5GiB" or "2GB", if it is not longer than plain bytes.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	best := strconv.FormatInt(int64(b), 10)
+	for _, units := range [][]string{iecUnits, siUnits} {
+		for exp := len(units) - 1; exp > 0; exp-- {
+			unit := byteUnits[strings.ToLower(units[exp])]
+			if b < unit && b > -unit {
+				continue
+			}
+			// 只使用不小于1的最大单位
+			s := strconv.FormatFloat(float64(b)/float64(unit), 'f', -1, 64)
+			if _, frac, _ := strings.Cut(s, "."); len(frac) <= 3 && len(s)+len(units[exp]) <= len(best) {
+				if exact, err := ParseBytes(strings.TrimPrefix(s, "-") + units[exp]); err == nil && (exact == b || -exact == b) {
+					best = s + units[exp]
+				}
+			}
+			break
+		}
+	}
+	return []byte(best), nil
+}
+
+// UnmarshalText parses b with ParseBytes, with an optional leading minus sign.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s, neg := strings.CutPrefix(strings.TrimSpace(string(text)), "-")
+	size, err := ParseBytes(s)
+	if err != nil {
+		return err
+	}
+	if neg {
+		size = -size
+	}
+	*b = size
+	return nil
+}
+
+// UnmarshalJSON accepts a number of bytes or a string for ParseBytes.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return b.UnmarshalText([]byte(s))
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidByteSize, data)
+	}
+	*b = ByteSize(n)
+	return nil
+}
@@ -0,0 +1,117 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		opts ByteFormat
+		want string
+	}{
+		{0, ByteFormat{Precision: 1}, "0 B"},
+		{1023, ByteFormat{Precision: 1}, "1023 B"},
+		{1536, ByteFormat{Precision: 1}, "1.5 KiB"},
+		{int64(1.5 * float64(GiB)), ByteFormat{Precision: 1}, "1.5 GiB"},
+		{1_500_000, ByteFormat{SI: true, Precision: 2}, "1.50 MB"},
+		{2 * int64(MiB), ByteFormat{Precision: 0, Compact: true}, "2MiB"},
+		{1048575, ByteFormat{Precision: 1}, "1.0 MiB"},
+		{-1536, ByteFormat{Precision: 1}, "-1.5 KiB"},
+		{int64(EiB) * 7, ByteFormat{Precision: 1}, "7.0 EiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytesWithOptions(tt.n, tt.opts); got != tt.want {
+			t.Errorf("FormatBytesWithOptions(%d, %+v) = %q, want %q", tt.n, tt.opts, got, tt.want)
+		}
+	}
+	if got := FormatBytes(1536); got != "1.5 KiB" {
+		t.Errorf("FormatBytes() = %q", got)
+	}
+	if got := (3 * MiB).String(); got != "3.0 MiB" {
+		t.Errorf("ByteSize.String() = %q", got)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ByteSize
+	}{
+		{"4096", 4096},
+		{"1.5GiB", GiB + 512*MiB},
+		{"1.5 GiB", GiB + 512*MiB},
+		{"10MB", 10 * MB},
+		{"10mb", 10 * MB},
+		{"512k", 512 * KiB},
+		{"2Gi", 2 * GiB},
+		{"1_000 B", 1000},
+		{"0.5kB", 500},
+		{" 8 bytes ", 8},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytes(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "GiB", "1.5 XB", "-1KiB", "1.2.3MB", "16EiB", "99999999999EB"} {
+		if _, err := ParseBytes(in); !errors.Is(err, ErrInvalidByteSize) {
+			t.Errorf("ParseBytes(%q) error = %v, want ErrInvalidByteSize", in, err)
+		}
+	}
+}
+
+func TestByteSizeMarshal(t *testing.T) {
+	for _, size := range []ByteSize{0, 100, KiB, GiB + 512*MiB, 1234567, -2 * MiB} {
+		text, err := size.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ByteSize
+		if err := got.UnmarshalText(text); err != nil || got != size {
+			t.Errorf("round trip of %d via %q = %d, %v", size, text, got, err)
+		}
+	}
+	if text, _ := (GiB + 512*MiB).MarshalText(); string(text) != "1.5GiB" {
+		t.Errorf("MarshalText() = %q, want 1.5GiB", text)
+	}
+
+	type config struct {
+		Max  ByteSize `json:"max" yaml:"max"`
+		Min  ByteSize `json:"min" yaml:"min"`
+		Size ByteSize `json:"size" yaml:"size"`
+	}
+
+	var c config
+	if err := json.Unmarshal([]byte(`{"max": "10MiB", "min": 512, "size": "1 GB"}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Max != 10*MiB || c.Min != 512 || c.Size != GB {
+		t.Errorf("json.Unmarshal() = %+v", c)
+	}
+	data, err := json.Marshal(c)
+	if err != nil || string(data) != `{"max":"10MiB","min":"512","size":"1GB"}` {
+		t.Errorf("json.Marshal() = %s, %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"max": true}`), &c); !errors.Is(err, ErrInvalidByteSize) {
+		t.Errorf("json.Unmarshal(bool) error = %v", err)
+	}
+
+	c = config{}
+	if err := yaml.Unmarshal([]byte("max: 1.5GiB\nmin: 512\nsize: 2k\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Max != GiB+512*MiB || c.Min != 512 || c.Size != 2*KiB {
+		t.Errorf("yaml.Unmarshal() = %+v", c)
+	}
+	data, err = yaml.Marshal(c)
+	if err != nil || string(data) != "max: 1.5GiB\nmin: \"512\"\nsize: 2KiB\n" {
+		t.Errorf("yaml.Marshal() = %q, %v", data, err)
+	}
+}
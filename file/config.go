@@ -0,0 +1,284 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedFormat is returned when a config format cannot be detected from a file extension
+// or is not supported.
+var ErrUnsupportedFormat = errors.New("unsupported config format")
+
+// Format is the encoding of a config file.
+//
+// 配置文件格式
+type Format int
+
+const (
+	// FormatAuto detects the format from the file extension.
+	FormatAuto Format = iota
+	// FormatJSON is JSON, used for .json files.
+	FormatJSON
+	// FormatYAML is YAML, used for .yaml and .yml files.
+	FormatYAML
+	// FormatTOML is TOML, used for .toml files.
+	FormatTOML
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatJSON:
+		return "json"
+	case FormatYAML:
+		return "yaml"
+	case FormatTOML:
+		return "toml"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// DetectFormat returns the config format of path from its extension, compared case-insensitively.
+//
+// 根据扩展名判断配置文件格式
+func DetectFormat(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return FormatAuto, fmt.Errorf("%s: %w %q", path, ErrUnsupportedFormat, ext)
+	}
+}
+
+// ConfigOptions configures LoadConfig, SaveConfig and Decode.
+//
+// 配置文件读写的配置项
+type ConfigOptions struct {
+	// Format overrides the format detected from the file extension.
+	Format Format
+	// Strict rejects unknown fields and, for JSON, trailing data after the value.
+	Strict bool
+	// Indent is the indentation, for YAML only its length is used. Defaults to two spaces.
+	// TOML only indents the keys of nested tables, and only if Indent is set.
+	Indent string
+	// Perm is used when the file is created, existing files keep their permissions. Defaults to 0o644.
+	Perm os.FileMode
+}
+
+// ReadJSON reads the JSON file at path into a T.
+//
+// 读取JSON文件到结构体
+// 示例:
+//
+//	cfg, err := file.ReadJSON[Config]("config.json")
+func ReadJSON[T any](path string) (T, error) {
+	return LoadConfig[T](path, ConfigOptions{Format: FormatJSON})
+}
+
+// WriteJSON atomically writes v as JSON to the file at path, indented by indent unless it is empty.
+//
+// 以JSON格式原子写入文件
+func WriteJSON(path string, v any, indent string) error {
+	data, err := encodeJSON(v, indent)
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, data, 0o644)
+}
+
+// ReadYAML reads the YAML file at path into a T.
+//
+// 读取YAML文件到结构体
+func ReadYAML[T any](path string) (T, error) {
+	return LoadConfig[T](path, ConfigOptions{Format: FormatYAML})
+}
+
+// WriteYAML atomically writes v as YAML to the file at path, indented by indent spaces (2 if indent <= 0).
+//
+// 以YAML格式原子写入文件
+func WriteYAML(path string, v any, indent int) error {
+	data, err := encodeYAML(v, indent)
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, data, 0o644)
+}
+
+// ReadTOML reads the TOML file at path into a T.
+//
+// 读取TOML文件到结构体
+func ReadTOML[T any](path string) (T, error) {
+	return LoadConfig[T](path, ConfigOptions{Format: FormatTOML})
+}
+
+// WriteTOML atomically writes v as TOML to the file at path.
+//
+// 以TOML格式原子写入文件
+func WriteTOML(path string, v any) error {
+	data, err := encodeTOML(v, "")
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, data, 0o644)
+}
+
+// LoadConfig reads the config file at path into a T, detecting the format from the extension
+// unless opts.Format is set: JSON (.json), YAML (.yaml, .yml) and TOML (.toml) are supported,
+// other extensions fail with ErrUnsupportedFormat. Decoding errors are prefixed with the path.
+//
+// 读取配置文件，根据扩展名判断格式（JSON、YAML、TOML），可选严格模式拒绝未知字段
+// 示例:
+//
+//	cfg, err := file.LoadConfig[Config]("config.yaml", file.ConfigOptions{Strict: true})
+func LoadConfig[T any](path string, opts ConfigOptions) (T, error) {
+	var v T
+	format, err := configFormat(path, opts.Format)
+	if err != nil {
+		return v, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return v, err
+	}
+	defer f.Close()
+	if err := Decode(f, &v, ConfigOptions{Format: format, Strict: opts.Strict}); err != nil {
+		return v, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+// SaveConfig atomically writes v to the config file at path, detecting the format from the extension
+// unless opts.Format is set.
+//
+// 原子写入配置文件，根据扩展名判断格式
+func SaveConfig(path string, v any, opts ConfigOptions) error {
+	format, err := configFormat(path, opts.Format)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	switch format {
+	case FormatJSON:
+		indent := opts.Indent
+		if indent == "" {
+			indent = "  "
+		}
+		data, err = encodeJSON(v, indent)
+	case FormatTOML:
+		data, err = encodeTOML(v, opts.Indent)
+	default:
+		data, err = encodeYAML(v, len(opts.Indent))
+	}
+	if err != nil {
+		return err
+	}
+	perm := opts.Perm
+	if perm == 0 {
+		perm = 0o644
+	}
+	return WriteAtomic(path, data, perm)
+}
+
+// Decode decodes a single config document from r into v. opts.Format must be set.
+//
+// 从io.Reader解码配置
+func Decode(r io.Reader, v any, opts ConfigOptions) error {
+	switch opts.Format {
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		if opts.Strict {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(v); err != nil {
+			return err
+		}
+		if opts.Strict && dec.More() {
+			return errors.New("json: unexpected data after the top-level value")
+		}
+		return nil
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(opts.Strict)
+		// 空文件视为空配置
+		if err := dec.Decode(v); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	case FormatTOML:
+		dec := toml.NewDecoder(r)
+		if opts.Strict {
+			dec.DisallowUnknownFields()
+		}
+		err := dec.Decode(v)
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) {
+			// 默认的错误信息不包含未知字段的名称
+			return fmt.Errorf("toml: unknown fields:\n%s", strict.String())
+		}
+		return err
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, opts.Format)
+	}
+}
+
+func configFormat(path string, format Format) (Format, error) {
+	if format != FormatAuto {
+		return format, nil
+	}
+	return DetectFormat(path)
+}
+
+func encodeJSON(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeYAML(v any, indent int) ([]byte, error) {
+	if indent <= 0 {
+		indent = 2
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeTOML(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	if indent != "" {
+		enc.SetIndentTables(true)
+		enc.SetIndentSymbol(indent)
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
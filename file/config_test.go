@@ -0,0 +1,127 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Name  string            `json:"name" yaml:"name" toml:"name"`
+	Port  int               `json:"port" yaml:"port" toml:"port"`
+	Tags  []string          `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
+	Extra map[string]string `json:"extra,omitempty" yaml:"extra,omitempty" toml:"extra,omitempty"`
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	want := testConfig{Name: "a<b", Port: 8080, Tags: []string{"x"}}
+	if err := WriteJSON(path, want, "  "); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "{\n  \"name\": \"a<b\",\n  \"port\": 8080,\n  \"tags\": [\n    \"x\"\n  ]\n}\n")
+	got, err := ReadJSON[testConfig](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	want := testConfig{Name: "svc", Port: 80, Extra: map[string]string{"k": "v"}}
+	if err := WriteYAML(path, want, 4); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "name: svc\nport: 80\nextra:\n    k: v\n")
+	got, err := ReadYAML[testConfig](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	want := testConfig{Name: "svc", Port: 80, Tags: []string{"a", "b"}, Extra: map[string]string{"k": "v"}}
+	if err := WriteTOML(path, want); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "name = 'svc'\nport = 80\ntags = ['a', 'b']\n\n[extra]\nk = 'v'\n")
+	got, err := ReadTOML[testConfig](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadSaveConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig{Name: "svc", Port: 1}
+	for _, name := range []string{"c.json", "c.yml", "C.YAML", "c.toml"} {
+		path := filepath.Join(dir, name)
+		if err := SaveConfig(path, cfg, ConfigOptions{Perm: 0o600}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := LoadConfig[testConfig](path, ConfigOptions{Strict: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, cfg) {
+			t.Errorf("%s: got %+v", name, got)
+		}
+	}
+
+	if err := SaveConfig(filepath.Join(dir, "c.ini"), cfg, ConfigOptions{}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("ini: got %v, want ErrUnsupportedFormat", err)
+	}
+	txt := filepath.Join(dir, "config.txt")
+	if err := SaveConfig(txt, cfg, ConfigOptions{Format: FormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig[testConfig](txt, ConfigOptions{Format: FormatJSON}); err != nil {
+		t.Errorf("explicit format: %v", err)
+	}
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "c.json")
+	writeFile(t, jsonPath, `{"name": "a", "unknown": 1}`)
+	yamlPath := filepath.Join(dir, "c.yaml")
+	writeFile(t, yamlPath, "name: a\nunknown: 1\n")
+	tomlPath := filepath.Join(dir, "c.toml")
+	writeFile(t, tomlPath, "name = 'a'\nunknown = 1\n")
+
+	for _, path := range []string{jsonPath, yamlPath, tomlPath} {
+		if _, err := LoadConfig[testConfig](path, ConfigOptions{}); err != nil {
+			t.Errorf("%s lenient: %v", path, err)
+		}
+		_, err := LoadConfig[testConfig](path, ConfigOptions{Strict: true})
+		if err == nil || !strings.Contains(err.Error(), "unknown") || !strings.HasPrefix(err.Error(), path) {
+			t.Errorf("%s strict: got %v", path, err)
+		}
+	}
+
+	writeFile(t, jsonPath, `{"name": "a"} {"name": "b"}`)
+	if _, err := LoadConfig[testConfig](jsonPath, ConfigOptions{Strict: true}); err == nil {
+		t.Error("strict JSON should reject trailing data")
+	}
+
+	writeFile(t, yamlPath, "")
+	if got, err := ReadYAML[testConfig](yamlPath); err != nil || !reflect.DeepEqual(got, testConfig{}) {
+		t.Errorf("empty yaml: got %+v, %v", got, err)
+	}
+	if _, err := ReadJSON[testConfig](filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("missing: got %v", err)
+	}
+}
@@ -0,0 +1,425 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errIsDir  = errors.New("is a directory")
+	errNotDir = errors.New("not a directory")
+	// errSameFile 源和目标是同一个文件，写入目标会先清空源
+	errSameFile = errors.New("source and destination are the same file")
+)
+
+// OverwritePolicy decides what happens when a copy or move destination already exists.
+//
+// 目标文件已存在时的处理策略
+type OverwritePolicy int
+
+const (
+	// OverwriteAlways replaces existing files.
+	OverwriteAlways OverwritePolicy = iota
+	// OverwriteSkip keeps existing files.
+	OverwriteSkip
+	// OverwriteNewer replaces existing files only if the source is more recently modified.
+	OverwriteNewer
+	// OverwriteError fails with an error wrapping fs.ErrExist.
+	OverwriteError
+)
+
+// CopyProgress reports the progress of a copy.
+//
+// 复制进度
+type CopyProgress struct {
+	// Path is the source file being copied.
+	Path string
+	// Bytes is the number of bytes copied so far, over all files.
+	Bytes int64
+	// Files is the number of files completely copied so far.
+	Files int
+}
+
+// CopyOptions configures Copy, CopyDir and Move.
+// Permission bits are always copied.
+//
+// 复制的配置项
+type CopyOptions struct {
+	// Overwrite is applied to existing destination files.
+	Overwrite OverwritePolicy
+	// PreserveTimes copies access and modification times.
+	PreserveTimes bool
+	// KeepSymlinks recreates symlinks as symlinks instead of copying what they point to.
+	KeepSymlinks bool
+	// Include, if not empty, restricts CopyDir to the files matching one of these globs (see MatchGlob),
+	// relative to the source directory.
+	Include []string
+	// Exclude skips the files and directories matching one of these globs.
+	Exclude []string
+	// Progress, if set, is called as data is copied and after each file.
+	Progress func(p CopyProgress)
+}
+
+// copier carries the state shared by the files of one copy operation.
+type copier struct {
+	ctx      context.Context
+	src      fs.FS
+	dst      FS
+	opts     CopyOptions
+	progress CopyProgress
+	buf      []byte
+}
+
+func newCopier(ctx context.Context, opts CopyOptions) *copier {
+	return newCopierFS(ctx, hostFS, hostFS, opts)
+}
+
+func newCopierFS(ctx context.Context, srcFS fs.FS, dstFS FS, opts CopyOptions) *copier {
+	return &copier{ctx: ctx, src: srcFS, dst: dstFS, opts: opts, buf: make([]byte, 128*1024)}
+}
+
+// Copy copies the file src to dst. Symlinks are followed unless KeepSymlinks is set.
+// It fails if dst is src itself, e.g. through a hard link or a symlink.
+//
+// 复制单个文件
+// 示例:
+//
+//	err := file.Copy(ctx, "a.txt", "backup/a.txt", file.CopyOptions{PreserveTimes: true})
+func Copy(ctx context.Context, src, dst string, opts CopyOptions) error {
+	_, err := newCopier(ctx, opts).copyEntry(src, dst)
+	return err
+}
+
+// CopyDir copies the directory tree src to dst, merging into dst if it exists.
+// Directories are created as needed; with Include set, directories without matching files are still created.
+//
+// 递归复制目录
+// 示例:
+//
+//	err := file.CopyDir(ctx, "dist", "/srv/www", file.CopyOptions{
+//		Overwrite: file.OverwriteNewer,
+//		Exclude:   []string{"**/*.map"},
+//	})
+func CopyDir(ctx context.Context, src, dst string, opts CopyOptions) error {
+	return newCopier(ctx, opts).copyDir(src, dst, nil)
+}
+
+// CopyFS copies the file src of srcFS to dst in dstFS, which may be a different filesystem.
+// Symlinks are only kept if srcFS can read them, see FS.
+//
+// 在文件系统之间复制单个文件
+// 示例:
+//
+//	err := file.CopyFS(ctx, embedded, "defaults/app.yaml", file.DirFS(configDir), "app.yaml", file.CopyOptions{})
+func CopyFS(ctx context.Context, srcFS fs.FS, src string, dstFS FS, dst string, opts CopyOptions) error {
+	_, err := newCopierFS(ctx, srcFS, dstFS, opts).copyEntry(src, dst)
+	return err
+}
+
+// CopyDirFS copies the directory tree src of srcFS to dst in dstFS, see CopyDir.
+//
+// 在文件系统之间递归复制目录
+func CopyDirFS(ctx context.Context, srcFS fs.FS, src string, dstFS FS, dst string, opts CopyOptions) error {
+	return newCopierFS(ctx, srcFS, dstFS, opts).copyDir(src, dst, nil)
+}
+
+// Move moves src to dst, which may be a file or a directory. It renames when possible and falls back
+// to copying and deleting when src and dst are on different filesystems or dst already exists.
+// Files skipped because of the overwrite policy are left in src. Symlinks are always moved as symlinks.
+// Moving a file onto itself fails and leaves it in place.
+//
+// 移动文件或目录，跨文件系统时退化为复制后删除
+func Move(ctx context.Context, src, dst string, opts CopyOptions) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		err := os.Rename(src, dst)
+		if err == nil || !isCrossDevice(err) {
+			return err
+		}
+	}
+
+	opts.KeepSymlinks = true
+	c := newCopier(ctx, opts)
+	if !info.IsDir() {
+		copied, err := c.copyEntry(src, dst)
+		if err != nil || !copied {
+			return err
+		}
+		return os.Remove(src)
+	}
+
+	var dirs []string
+	err = c.copyDir(src, dst, func(e Entry) error {
+		if e.IsDir() {
+			dirs = append(dirs, e.Path)
+			return nil
+		}
+		return os.Remove(e.Path)
+	})
+	if err != nil {
+		return err
+	}
+	// 从最深的目录开始删除，仍有内容的目录保留
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+	_ = os.Remove(src)
+	return nil
+}
+
+// copyDir copies the tree, calling done for every directory and every file that was copied.
+func (c *copier) copyDir(src, dst string, done func(e Entry) error) error {
+	info, err := fs.Stat(c.src, src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &fs.PathError{Op: "copydir", Path: src, Err: errNotDir}
+	}
+	if c.within(src, dst) {
+		return &fs.PathError{Op: "copydir", Path: dst, Err: errors.New("destination is inside the source directory")}
+	}
+	if err := c.dst.MkdirAll(dst, info.Mode().Perm()|0o700); err != nil {
+		return err
+	}
+
+	walkOpts := WalkOptions{FollowSymlinks: !c.opts.KeepSymlinks}
+	if len(c.opts.Exclude) > 0 {
+		walkOpts.Prune = ByGlob(c.opts.Exclude...)
+		walkOpts.Filters = append(walkOpts.Filters, Not(ByGlob(c.opts.Exclude...)))
+	}
+	if len(c.opts.Include) > 0 {
+		walkOpts.Filters = append(walkOpts.Filters, Or(DirsOnly(), ByGlob(c.opts.Include...)))
+	}
+
+	type dirTimes struct {
+		path string
+		info fs.FileInfo
+	}
+	dirs := []dirTimes{{dst, info}}
+	err = WalkFS(c.src, src, walkOpts, func(e Entry) error {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+		target := joinPath(c.dst, dst, e.Rel)
+		if e.IsDir() {
+			if err := c.dst.MkdirAll(target, e.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTimes{target, e.FileInfo})
+		} else {
+			copied, err := c.copyEntry(e.Path, target)
+			if err != nil || !copied {
+				return err
+			}
+		}
+		if done != nil {
+			return done(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 目录内容写完后再设置目录的权限和时间
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if err := c.dst.Chmod(d.path, d.info.Mode().Perm()); err != nil {
+			return err
+		}
+		if c.opts.PreserveTimes {
+			if err := c.dst.Chtimes(d.path, d.info.ModTime(), d.info.ModTime()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyEntry copies a file or symlink, reporting whether it was copied or skipped by the overwrite policy.
+func (c *copier) copyEntry(src, dst string) (bool, error) {
+	if err := c.ctx.Err(); err != nil {
+		return false, err
+	}
+	info, err := lstat(c.src, src)
+	if err != nil {
+		return false, err
+	}
+	isLink := info.Mode()&fs.ModeSymlink != 0
+	if isLink && !c.opts.KeepSymlinks {
+		if info, err = fs.Stat(c.src, src); err != nil {
+			return false, err
+		}
+		isLink = false
+	}
+	if info.IsDir() {
+		return false, &fs.PathError{Op: "copy", Path: src, Err: errIsDir}
+	}
+	if c.sameFile(src, info, dst) {
+		return false, &fs.PathError{Op: "copy", Path: dst, Err: errSameFile}
+	}
+
+	ok, err := c.shouldWrite(info, dst)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := c.dst.MkdirAll(dirPath(c.dst, dst), 0o755); err != nil {
+		return false, err
+	}
+
+	if isLink {
+		target, err := readlink(c.src, src)
+		if err != nil {
+			return false, err
+		}
+		if err := c.dst.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		if err := c.dst.Symlink(target, dst); err != nil {
+			return false, err
+		}
+	} else if err := c.copyFile(src, dst, info); err != nil {
+		return false, err
+	}
+
+	c.progress.Files++
+	c.report(src)
+	return true, nil
+}
+
+// sameFile reports whether writing dst would destroy src: dst is src itself, a hard link to it,
+// a symlink to it, or the file a kept symlink src points to. Only host files can be compared.
+func (c *copier) sameFile(src string, info fs.FileInfo, dst string) bool {
+	existing, err := c.dst.Lstat(dst)
+	if err != nil {
+		return false
+	}
+	if os.SameFile(info, existing) {
+		return true
+	}
+	srcLink := info.Mode()&fs.ModeSymlink != 0
+	dstLink := existing.Mode()&fs.ModeSymlink != 0
+	switch {
+	case srcLink && dstLink:
+		// 两个链接互相替换不会丢失数据
+		return false
+	case dstLink:
+		// 打开dst会跟随链接写到它指向的文件
+		existing, err = fs.Stat(c.dst, dst)
+	case srcLink:
+		// dst会被删除后替换为链接，链接指向的正是dst
+		info, err = fs.Stat(c.src, src)
+	}
+	return err == nil && os.SameFile(info, existing)
+}
+
+func (c *copier) shouldWrite(src fs.FileInfo, dst string) (bool, error) {
+	existing, err := c.dst.Lstat(dst)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if existing.IsDir() {
+		return false, &fs.PathError{Op: "copy", Path: dst, Err: errIsDir}
+	}
+	switch c.opts.Overwrite {
+	case OverwriteSkip:
+		return false, nil
+	case OverwriteNewer:
+		return src.ModTime().After(existing.ModTime()), nil
+	case OverwriteError:
+		return false, &fs.PathError{Op: "copy", Path: dst, Err: fs.ErrExist}
+	default:
+		return true, nil
+	}
+}
+
+func (c *copier) copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := c.src.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := c.dst.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyBuffer(out, &progressReader{r: in, c: c, path: src}, c.buf); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := c.dst.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if c.opts.PreserveTimes {
+		return c.dst.Chtimes(dst, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+func (c *copier) report(path string) {
+	if c.opts.Progress != nil {
+		c.progress.Path = path
+		c.opts.Progress(c.progress)
+	}
+}
+
+// progressReader counts the bytes read, reports progress and stops when the context is done.
+type progressReader struct {
+	r    io.Reader
+	c    *copier
+	path string
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.c.progress.Bytes += int64(n)
+		r.c.report(r.path)
+	}
+	return n, err
+}
+
+// within reports whether dst is src or inside src. Paths of different filesystems never overlap.
+func (c *copier) within(src, dst string) bool {
+	switch {
+	case c.src == fs.FS(hostFS) && c.dst == FS(hostFS):
+		return isWithin(src, dst)
+	case !sameFS(c.src, c.dst):
+		return false
+	}
+	_, ok := trimDir(src, dst)
+	return ok || src == "."
+}
+
+// isWithin reports whether path is dir or inside dir.
+func isWithin(dir, path string) bool {
+	absDir, err1 := filepath.Abs(dir)
+	absPath, err2 := filepath.Abs(path)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,221 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	root := makeTree(t, map[string]string{"src.txt": "hello"})
+	src := filepath.Join(root, "src.txt")
+	dst := filepath.Join(root, "out", "dst.txt")
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(src, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var last CopyProgress
+	err := Copy(context.Background(), src, dst, CopyOptions{
+		PreserveTimes: true,
+		Progress:      func(p CopyProgress) { last = p },
+	})
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	assertFileContent(t, dst, "hello")
+	if info, _ := os.Stat(dst); !info.ModTime().Equal(old) {
+		t.Errorf("Copy() mtime = %v, want %v", info.ModTime(), old)
+	}
+	if last.Files != 1 || last.Bytes != 5 {
+		t.Errorf("Copy() progress = %+v", last)
+	}
+}
+
+func TestCopyOverwrite(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		policy  OverwritePolicy
+		srcAge  time.Duration
+		want    string
+		wantErr error
+	}{
+		{"always", OverwriteAlways, time.Hour, "new", nil},
+		{"skip", OverwriteSkip, 0, "old", nil},
+		{"newer with older source", OverwriteNewer, time.Hour, "old", nil},
+		{"newer with newer source", OverwriteNewer, -time.Hour, "new", nil},
+		{"error", OverwriteError, 0, "old", fs.ErrExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTree(t, map[string]string{"src": "new", "dst": "old"})
+			src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+			mtime := time.Now().Add(-tt.srcAge)
+			if err := os.Chtimes(src, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			err := Copy(ctx, src, dst, CopyOptions{Overwrite: tt.policy})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Copy() error = %v, want %v", err, tt.wantErr)
+			}
+			assertFileContent(t, dst, tt.want)
+		})
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"src/a.txt":       "a",
+		"src/b.log":       "b",
+		"src/sub/c.txt":   "c",
+		"src/skip/d.txt":  "d",
+		"src/empty/":      "",
+		"dst/existing.md": "e",
+	})
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+
+	err := CopyDir(context.Background(), src, dst, CopyOptions{
+		Include: []string{"*.txt"},
+		Exclude: []string{"skip"},
+	})
+	if err != nil {
+		t.Fatalf("CopyDir() error = %v", err)
+	}
+	got, _ := Find(dst, WalkOptions{})
+	want := []string{"a.txt", "empty", "existing.md", "sub", "sub/c.txt"}
+	if !reflect.DeepEqual(rels(got), want) {
+		t.Errorf("CopyDir() tree = %v, want %v", rels(got), want)
+	}
+
+	if err := CopyDir(context.Background(), src, filepath.Join(src, "sub"), CopyOptions{}); err == nil {
+		t.Error("CopyDir() into itself should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := CopyDir(ctx, src, filepath.Join(root, "cancelled"), CopyOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("CopyDir() with cancelled context error = %v", err)
+	}
+}
+
+func TestCopyDirSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	root := makeTree(t, map[string]string{"src/target.txt": "t"})
+	src := filepath.Join(root, "src")
+	if err := os.Symlink("target.txt", filepath.Join(src, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := filepath.Join(root, "kept")
+	if err := CopyDir(context.Background(), src, kept, CopyOptions{KeepSymlinks: true}); err != nil {
+		t.Fatalf("CopyDir() error = %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(kept, "link.txt")); err != nil || target != "target.txt" {
+		t.Errorf("CopyDir() link = %q, %v", target, err)
+	}
+
+	followed := filepath.Join(root, "followed")
+	if err := CopyDir(context.Background(), src, followed, CopyOptions{}); err != nil {
+		t.Fatalf("CopyDir() error = %v", err)
+	}
+	if info, err := os.Lstat(filepath.Join(followed, "link.txt")); err != nil || info.Mode()&fs.ModeSymlink != 0 {
+		t.Errorf("CopyDir() should copy the link target, got %v, %v", info, err)
+	}
+	assertFileContent(t, filepath.Join(followed, "link.txt"), "t")
+}
+
+func TestCopySameFile(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// link 在root下创建dst，返回dst路径
+		link func(t *testing.T, src string) string
+		move bool
+	}{
+		{"itself", func(t *testing.T, src string) string { return src }, false},
+		{"hard link", func(t *testing.T, src string) string {
+			dst := src + ".hard"
+			if err := os.Link(src, dst); err != nil {
+				t.Skipf("hard links unsupported: %v", err)
+			}
+			return dst
+		}, false},
+		{"symlink", func(t *testing.T, src string) string {
+			if runtime.GOOS == "windows" {
+				t.Skip("symlinks need privileges on windows")
+			}
+			dst := src + ".link"
+			if err := os.Symlink(filepath.Base(src), dst); err != nil {
+				t.Fatal(err)
+			}
+			return dst
+		}, false},
+		{"move itself", func(t *testing.T, src string) string { return src }, true},
+		{"move hard link", func(t *testing.T, src string) string {
+			dst := src + ".hard"
+			if err := os.Link(src, dst); err != nil {
+				t.Skipf("hard links unsupported: %v", err)
+			}
+			return dst
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makeTree(t, map[string]string{"src.txt": "hello"})
+			src := filepath.Join(root, "src.txt")
+			dst := tt.link(t, src)
+
+			var err error
+			if tt.move {
+				err = Move(ctx, src, dst, CopyOptions{})
+			} else {
+				err = Copy(ctx, src, dst, CopyOptions{})
+			}
+			if !errors.Is(err, errSameFile) {
+				t.Errorf("error = %v, want %v", err, errSameFile)
+			}
+			// 源文件必须保持原样
+			assertFileContent(t, src, "hello")
+		})
+	}
+}
+
+func TestMove(t *testing.T) {
+	ctx := context.Background()
+	root := makeTree(t, map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "b",
+		"dir/sub/c.txt": "c",
+		"dst/b.txt":     "keep",
+	})
+
+	if err := Move(ctx, filepath.Join(root, "a.txt"), filepath.Join(root, "moved", "a.txt"), CopyOptions{}); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	assertFileContent(t, filepath.Join(root, "moved", "a.txt"), "a")
+	if ok, _ := IsPathExist(filepath.Join(root, "a.txt")); ok {
+		t.Error("Move() left the source file")
+	}
+
+	// 目标已存在时退化为复制后删除，跳过的文件保留在源目录
+	err := Move(ctx, filepath.Join(root, "dir"), filepath.Join(root, "dst"), CopyOptions{Overwrite: OverwriteSkip})
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	assertFileContent(t, filepath.Join(root, "dst", "b.txt"), "keep")
+	assertFileContent(t, filepath.Join(root, "dst", "sub", "c.txt"), "c")
+	got, _ := Find(filepath.Join(root, "dir"), WalkOptions{})
+	if want := []string{"b.txt"}; !reflect.DeepEqual(rels(got), want) {
+		t.Errorf("Move() left %v in source, want %v", rels(got), want)
+	}
+}
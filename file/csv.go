@@ -0,0 +1,599 @@
+package file
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOptions configures ReadCSV, WriteCSV, CSVReader and CSVWriter.
+//
+// CSV读写的配置项
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' by default. Use '\t' for tab separated files.
+	Comma rune
+	// Comment, if set, makes lines starting with it ignored when reading.
+	Comment rune
+	// Encoding is the text encoding of the file. When reading, EncodingUnknown (the zero value) detects it,
+	// including byte order marks, see NewUTF8Reader. When writing it means UTF-8 without byte order mark;
+	// use EncodingUTF8BOM or EncodingGBK for files opened by Excel.
+	Encoding Encoding
+	// TimeFormat is the layout of time.Time fields without a format tag option, time.RFC3339 by default.
+	// Times without a zone are read in the local time zone.
+	TimeFormat string
+	// TrimSpace removes the leading and trailing white space of cells before converting them.
+	TrimSpace bool
+	// Strict makes reading fail if a header column has no field or a field has no column.
+	Strict bool
+	// CRLF ends the written lines with \r\n instead of \n.
+	CRLF bool
+}
+
+// CSVError is a conversion error of a cell, reporting where it is.
+//
+// CSV单元格转换错误，包含行号和列号
+type CSVError struct {
+	// Line is the line of the cell in the file, starting at 1.
+	Line int
+	// Column is the index of the cell in its record, starting at 1.
+	Column int
+	// Header is the name of the column.
+	Header string
+	Err    error
+}
+
+func (e *CSVError) Error() string {
+	return fmt.Sprintf("line %d, column %d (%s): %v", e.Line, e.Column, e.Header, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// csvField is a struct field mapped to a CSV column.
+type csvField struct {
+	name   string
+	index  []int
+	format string
+}
+
+// csvFields returns the fields of the struct type t, or of the struct t points to, in declaration order.
+// Fields are named by their `csv:"name"` tag or else their Go name; `csv:"-"` skips a field and
+// `csv:"name,format=2006-01-02"` sets the layout of a time field. Fields of embedded structs are included.
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csv: %v is not a struct", t)
+	}
+	var fields []csvField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && f.Type.Kind() == reflect.Struct) || viaPointer(t, f.Index) {
+			continue
+		}
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		field := csvField{name: name, index: f.Index}
+		for _, opt := range strings.Split(opts, ",") {
+			if format, ok := strings.CutPrefix(opt, "format="); ok {
+				field.format = format
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// viaPointer reports whether the field at index is promoted through an embedded pointer,
+// which CSVReader would have to allocate.
+func viaPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Pointer {
+			return true
+		}
+		t = f.Type
+	}
+	return false
+}
+
+// CSVReader reads the records of a CSV file with a header line into structs of type T
+// (or pointers to structs), mapping columns to fields by name, see ReadCSV.
+//
+// 流式读取CSV到结构体
+// 示例:
+//
+//	r, err := file.OpenCSV[Order]("orders.csv", file.CSVOptions{})
+//	defer r.Close()
+//	for r.Next() {
+//		process(r.Row())
+//	}
+//	if err := r.Err(); err != nil { ... }
+type CSVReader[T any] struct {
+	r       *csv.Reader
+	closer  io.Closer
+	opts    CSVOptions
+	headers []string
+	// columns holds the field of each column, nil for columns without field.
+	columns []*csvField
+	row     T
+	line    int
+	err     error
+}
+
+// NewCSVReader reads the header line of r and returns a CSVReader for the records after it.
+//
+// 创建CSV读取器，读取表头
+func NewCSVReader[T any](r io.Reader, opts CSVOptions) (*CSVReader[T], error) {
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	if opts.Encoding == EncodingUnknown {
+		if r, _, err = NewUTF8Reader(r); err != nil {
+			return nil, err
+		}
+	} else {
+		r = NewDecodeReader(r, opts.Encoding)
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.Comment = opts.Comment
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv: missing header line")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CSVReader[T]{r: cr, opts: opts, headers: make([]string, len(header)), columns: make([]*csvField, len(header))}
+	used := map[string]bool{}
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		c.headers[i] = h
+		if f := matchCSVField(fields, h); f != nil {
+			c.columns[i] = f
+			used[f.name] = true
+		} else if opts.Strict {
+			return nil, fmt.Errorf("csv: column %q has no field", h)
+		}
+	}
+	if opts.Strict {
+		for _, f := range fields {
+			if !used[f.name] {
+				return nil, fmt.Errorf("csv: field %q has no column", f.name)
+			}
+		}
+	}
+	return c, nil
+}
+
+// matchCSVField returns the field named name, comparing exactly first and then case-insensitively.
+func matchCSVField(fields []csvField, name string) *csvField {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// OpenCSV opens the CSV file at path and returns a CSVReader for it. Close closes the file.
+//
+// 打开CSV文件并创建读取器
+func OpenCSV[T any](path string, opts CSVOptions) (*CSVReader[T], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewCSVReader[T](f, opts)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Headers returns the column names of the header line.
+func (c *CSVReader[T]) Headers() []string {
+	return c.headers
+}
+
+// Next reads the next record into Row. It returns false at the end of the file or on error.
+//
+// 读取下一行，结束或出错时返回false
+func (c *CSVReader[T]) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	record, err := c.r.Read()
+	if err != nil {
+		if err != io.EOF {
+			c.err = err
+		}
+		return false
+	}
+	c.line, _ = c.r.FieldPos(0)
+
+	var row T
+	v := reflect.ValueOf(&row).Elem()
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	for i, f := range c.columns {
+		if f == nil {
+			continue
+		}
+		var cell string
+		if i < len(record) {
+			cell = record[i]
+		} else if c.opts.Strict {
+			c.err = &CSVError{Line: c.line, Column: i + 1, Header: c.headers[i], Err: errors.New("missing cell")}
+			return false
+		}
+		if c.opts.TrimSpace {
+			cell = strings.TrimSpace(cell)
+		}
+		format := f.format
+		if format == "" {
+			format = c.opts.TimeFormat
+		}
+		if err := setCSVValue(v.FieldByIndex(f.index), cell, format); err != nil {
+			line, col := c.line, i+1
+			if i < len(record) {
+				line, _ = c.r.FieldPos(i)
+			}
+			c.err = &CSVError{Line: line, Column: col, Header: c.headers[i], Err: err}
+			return false
+		}
+	}
+	c.row = row
+	return true
+}
+
+// Row returns the record read by the last call to Next.
+func (c *CSVReader[T]) Row() T {
+	return c.row
+}
+
+// Line returns the line of the record read by the last call to Next.
+func (c *CSVReader[T]) Line() int {
+	return c.line
+}
+
+// Err returns the error that stopped Next, or nil at the end of the file.
+func (c *CSVReader[T]) Err() error {
+	return c.err
+}
+
+// Close closes the file opened by OpenCSV. It does nothing for a reader created by NewCSVReader.
+func (c *CSVReader[T]) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// ReadCSV reads all records of the CSV file at path into structs of type T. The first line is
+// the header: each column is mapped to the field with the same name (exactly or else case-insensitively),
+// given by its `csv:"name"` tag or its Go name; `csv:"-"` skips a field. Columns without field are ignored.
+//
+// Fields can be strings, bools, integers, floats, time.Duration, time.Time (with TimeFormat or a
+// `csv:"name,format=2006-01-02"` tag option), types implementing encoding.TextUnmarshaler,
+// or pointers to those, which stay nil for empty cells. Conversion errors are *CSVError, with the
+// line and column of the cell. Use OpenCSV to stream large files.
+//
+// 读取CSV文件到结构体切片，按表头匹配字段
+// 示例:
+//
+//	type Order struct {
+//		ID      int       `csv:"id"`
+//		Amount  float64   `csv:"amount"`
+//		Created time.Time `csv:"created,format=2006-01-02 15:04:05"`
+//		Note    *string   `csv:"note"`
+//	}
+//	orders, err := file.ReadCSV[Order]("orders.csv", file.CSVOptions{})
+func ReadCSV[T any](path string, opts CSVOptions) ([]T, error) {
+	r, err := OpenCSV[T](path, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var rows []T
+	for r.Next() {
+		rows = append(rows, r.Row())
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rows, nil
+}
+
+// CSVWriter writes structs of type T (or pointers to structs) as CSV records, starting with a header
+// line of the field names, see WriteCSV.
+//
+// 将结构体流式写入CSV
+type CSVWriter[T any] struct {
+	w       *csv.Writer
+	enc     io.WriteCloser
+	opts    CSVOptions
+	fields  []csvField
+	record  []string
+	started bool
+}
+
+// NewCSVWriter returns a CSVWriter writing to w. The header is written with the first row, or by Flush.
+//
+// 创建CSV写入器
+func NewCSVWriter[T any](w io.Writer, opts CSVOptions) (*CSVWriter[T], error) {
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	cw := &CSVWriter[T]{opts: opts, fields: fields, record: make([]string, len(fields))}
+	if opts.Encoding != EncodingUnknown && opts.Encoding != EncodingUTF8 {
+		cw.enc = NewEncodeWriter(w, opts.Encoding)
+		w = cw.enc
+	}
+	cw.w = csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.w.Comma = opts.Comma
+	}
+	cw.w.UseCRLF = opts.CRLF
+	return cw, nil
+}
+
+func (c *CSVWriter[T]) header() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	for i, f := range c.fields {
+		c.record[i] = f.name
+	}
+	return c.w.Write(c.record)
+}
+
+// Write writes row as a record. Nil pointers give empty cells.
+func (c *CSVWriter[T]) Write(row T) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(&row).Elem()
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return errors.New("csv: nil row")
+		}
+		v = v.Elem()
+	}
+	for i, f := range c.fields {
+		format := f.format
+		if format == "" {
+			format = c.opts.TimeFormat
+		}
+		s, err := formatCSVValue(v.FieldByIndex(f.index), format)
+		if err != nil {
+			return fmt.Errorf("csv: field %s: %w", f.name, err)
+		}
+		c.record[i] = s
+	}
+	return c.w.Write(c.record)
+}
+
+// Flush writes the buffered records, and the header if no row was written.
+// It does not close the underlying writer.
+func (c *CSVWriter[T]) Flush() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	if c.enc != nil {
+		// 编码器的Close只刷新缓冲，不关闭底层的Writer
+		return c.enc.Close()
+	}
+	return nil
+}
+
+// WriteCSV atomically writes rows to the CSV file at path, with a header line of the field names
+// (see ReadCSV for the tags and supported types).
+//
+// 将结构体切片写入CSV文件，表头来自csv标签
+// 示例:
+//
+//	err := file.WriteCSV("orders.csv", orders, file.CSVOptions{Encoding: file.EncodingUTF8BOM})
+func WriteCSV[T any](path string, rows []T, opts CSVOptions) error {
+	aw, err := NewAtomicWriter(path, 0o644, AtomicOptions{})
+	if err != nil {
+		return err
+	}
+	w, err := NewCSVWriter[T](aw, opts)
+	if err == nil {
+		for _, row := range rows {
+			if err = w.Write(row); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		_ = aw.Abort()
+		return err
+	}
+	return aw.Close()
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setCSVValue converts the cell s into v.
+func setCSVValue(v reflect.Value, s string, timeFormat string) error {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := setCSVValue(p.Elem(), s, timeFormat); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		if s == "" {
+			v.Set(reflect.Zero(timeType))
+			return nil
+		}
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		t, err := time.ParseInLocation(timeFormat, s, time.Local)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case reflect.PointerTo(v.Type()).Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if s == "" && v.Kind() != reflect.String {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := parseCSVBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", v.Type())
+	}
+	return nil
+}
+
+// parseCSVBool accepts the values of strconv.ParseBool plus yes/no and y/n.
+func parseCSVBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// formatCSVValue converts v into a cell.
+func formatCSVValue(v reflect.Value, timeFormat string) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		if timeFormat == "" {
+			timeFormat = time.RFC3339
+		}
+		return t.Format(timeFormat), nil
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String(), nil
+	case v.Type().Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	case v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textMarshalerType):
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported field type %v", v.Type())
+}
@@ -0,0 +1,111 @@
+package file
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type csvOrder struct {
+	ID      int       `csv:"id"`
+	Name    string    `csv:"name"`
+	Amount  float64   `csv:"amount"`
+	Paid    bool      `csv:"paid"`
+	Created time.Time `csv:"created,format=2006-01-02 15:04"`
+	Size    ByteSize  `csv:"size"`
+	Note    *string   `csv:"note"`
+	Secret  string    `csv:"-"`
+	Extra   int
+}
+
+func TestReadCSV(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "orders.csv")
+	data := "\ufeffid,Name,amount,paid,created,size,note,unknown\n" +
+		"1,苹果,1.5,yes,2024-05-01 10:00,1.5KiB,hi,x\n" +
+		"2,\"a, b\",2,false,,512,,y\n"
+	if err := WriteAtomic(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := ReadCSV[csvOrder](p, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows", len(rows))
+	}
+	r := rows[0]
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	if r.ID != 1 || r.Name != "苹果" || r.Amount != 1.5 || !r.Paid || !r.Created.Equal(created) || r.Size != 1536 || r.Note == nil || *r.Note != "hi" {
+		t.Errorf("row 1 = %+v", r)
+	}
+	r = rows[1]
+	if r.Name != "a, b" || r.Paid || !r.Created.IsZero() || r.Size != 512 || r.Note != nil {
+		t.Errorf("row 2 = %+v", r)
+	}
+
+	if _, err := ReadCSV[csvOrder](p, CSVOptions{Strict: true}); err == nil {
+		t.Error("Strict read with unknown column succeeded")
+	}
+}
+
+func TestReadCSVError(t *testing.T) {
+	r, err := NewCSVReader[csvOrder](strings.NewReader("id;amount\n1;2\n2;abc\n"), CSVOptions{Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for r.Next() {
+		n++
+	}
+	var cerr *CSVError
+	if !errors.As(r.Err(), &cerr) {
+		t.Fatalf("Err() = %v", r.Err())
+	}
+	if n != 1 || cerr.Line != 3 || cerr.Column != 2 || cerr.Header != "amount" {
+		t.Errorf("rows = %d, error = %v", n, cerr)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "out.csv")
+	note := "x\ny"
+	rows := []*csvOrder{
+		{ID: 1, Name: "a,b", Amount: 0.25, Paid: true, Created: time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local), Size: 2 * KiB, Note: &note, Secret: "s"},
+		{ID: 2},
+	}
+	if err := WriteCSV(p, rows, CSVOptions{Encoding: EncodingUTF8BOM}); err != nil {
+		t.Fatal(err)
+	}
+	want := "\ufeffid,name,amount,paid,created,size,note,Extra\n" +
+		"1,\"a,b\",0.25,true,2024-05-01 10:00,2KiB,\"x\ny\",0\n" +
+		"2,,0,false,,0,,0\n"
+	assertFileContent(t, p, want)
+
+	got, err := ReadCSV[*csvOrder](p, CSVOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || *got[0].Note != note || got[0].Secret != "" || got[0].Size != 2*KiB {
+		t.Errorf("read back %+v", got)
+	}
+}
+
+func TestWriteCSVGBK(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "gbk.csv")
+	type row struct {
+		Name string `csv:"名称"`
+	}
+	if err := WriteCSV(p, []row{{"中文"}}, CSVOptions{Encoding: EncodingGBK, CRLF: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadCSV[row](p, CSVOptions{Encoding: EncodingGBK})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "中文" {
+		t.Errorf("read back %+v", got)
+	}
+}
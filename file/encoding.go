@@ -0,0 +1,321 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// ErrUnknownEncoding is returned when the encoding of a text cannot be detected.
+var ErrUnknownEncoding = errors.New("unknown text encoding")
+
+// Encoding is a text encoding.
+//
+// 文本编码
+type Encoding int
+
+const (
+	// EncodingUnknown is returned by DetectEncoding for data that is not text in a supported encoding.
+	EncodingUnknown Encoding = iota
+	// EncodingUTF8 is UTF-8 without byte order mark. ASCII text is detected as UTF-8.
+	EncodingUTF8
+	// EncodingUTF8BOM is UTF-8 starting with a byte order mark, which Excel needs to open UTF-8 CSV files.
+	EncodingUTF8BOM
+	// EncodingUTF16LE is little endian UTF-16, the "Unicode" of Windows. It is written with a byte order mark.
+	EncodingUTF16LE
+	// EncodingUTF16BE is big endian UTF-16. It is written with a byte order mark.
+	EncodingUTF16BE
+	// EncodingGBK is GBK, the "ANSI" code page 936 of Simplified Chinese Windows.
+	EncodingGBK
+	// EncodingGB18030 is GB18030, the superset of GBK covering all of Unicode.
+	EncodingGB18030
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUnknown:
+		return "unknown"
+	case EncodingUTF8:
+		return "utf-8"
+	case EncodingUTF8BOM:
+		return "utf-8-bom"
+	case EncodingUTF16LE:
+		return "utf-16le"
+	case EncodingUTF16BE:
+		return "utf-16be"
+	case EncodingGBK:
+		return "gbk"
+	case EncodingGB18030:
+		return "gb18030"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	}
+}
+
+// textEncoding returns the golang.org/x/text implementation of e.
+func (e Encoding) textEncoding() (encoding.Encoding, error) {
+	switch e {
+	case EncodingUTF8:
+		return encoding.Nop, nil
+	case EncodingUTF8BOM:
+		return unicode.UTF8BOM, nil
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil
+	case EncodingGBK:
+		return simplifiedchinese.GBK, nil
+	case EncodingGB18030:
+		return simplifiedchinese.GB18030, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownEncoding, e)
+	}
+}
+
+// DetectEncoding guesses the encoding of data from its byte order mark, or else from which encodings
+// it is valid in: UTF-8 first, then UTF-16 for text with many NUL bytes, then GBK, or GB18030
+// if four-byte sequences are present. A multi-byte sequence cut at the end of data is allowed,
+// so a prefix of a file can be passed. Binary data gives EncodingUnknown.
+//
+// 根据BOM和字节特征检测文本编码
+// 示例:
+//
+//	enc := file.DetectEncoding(data) // file.EncodingGBK
+func DetectEncoding(data []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(data, []byte("\xff\xfe")):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(data, []byte("\xfe\xff")):
+		return EncodingUTF16BE
+	case bytes.HasPrefix(data, []byte("\x84\x31\x95\x33")):
+		return EncodingGB18030
+	}
+	if enc := detectUTF16(data); enc != EncodingUnknown {
+		return enc
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return EncodingUnknown
+	}
+	if validUTF8Prefix(data) {
+		return EncodingUTF8
+	}
+	return detectGB(data)
+}
+
+// detectUTF16 recognises UTF-16 without byte order mark from the NUL bytes of mostly ASCII text.
+func detectUTF16(data []byte) Encoding {
+	pairs := len(data) / 2
+	if pairs < 2 {
+		return EncodingUnknown
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	var enc Encoding
+	switch {
+	case oddZeros*10 >= pairs*4 && evenZeros*20 < pairs:
+		enc = EncodingUTF16LE
+	case evenZeros*10 >= pairs*4 && oddZeros*20 < pairs:
+		enc = EncodingUTF16BE
+	default:
+		return EncodingUnknown
+	}
+	// 控制字符说明是二进制数据
+	for i := 0; i+1 < len(data); i += 2 {
+		u := uint16(data[i]) | uint16(data[i+1])<<8
+		if enc == EncodingUTF16BE {
+			u = uint16(data[i])<<8 | uint16(data[i+1])
+		}
+		if u < 0x20 && u != '\t' && u != '\n' && u != '\r' {
+			return EncodingUnknown
+		}
+	}
+	return enc
+}
+
+// validUTF8Prefix reports whether data is valid UTF-8, except possibly for a rune cut at its end.
+func validUTF8Prefix(data []byte) bool {
+	if utf8.Valid(data) {
+		return true
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			return !utf8.FullRune(data[len(data)-i:]) && utf8.Valid(data[:len(data)-i])
+		}
+	}
+	return false
+}
+
+// detectGB checks data against the byte ranges of GBK and GB18030.
+func detectGB(data []byte) Encoding {
+	enc := EncodingGBK
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c < 0x80:
+			i++
+			continue
+		case c == 0x80:
+			// 0x80在代码页936中是欧元符号
+			i++
+			continue
+		case c == 0xff:
+			return EncodingUnknown
+		}
+		if i+1 >= len(data) {
+			return enc
+		}
+		c2 := data[i+1]
+		switch {
+		case c2 >= 0x40 && c2 <= 0xfe && c2 != 0x7f:
+			i += 2
+		case c2 >= 0x30 && c2 <= 0x39:
+			// GB18030的四字节序列
+			if i+3 >= len(data) {
+				return EncodingGB18030
+			}
+			if data[i+2] < 0x81 || data[i+2] > 0xfe || data[i+3] < 0x30 || data[i+3] > 0x39 {
+				return EncodingUnknown
+			}
+			enc = EncodingGB18030
+			i += 4
+		default:
+			return EncodingUnknown
+		}
+	}
+	return enc
+}
+
+// NewDecodeReader returns a reader converting the text read from r from enc to UTF-8.
+// Byte order marks are removed. Invalid bytes become U+FFFD.
+//
+// 将enc编码的输入流式转换为UTF-8
+// 示例:
+//
+//	r := csv.NewReader(file.NewDecodeReader(f, file.EncodingGBK))
+func NewDecodeReader(r io.Reader, enc Encoding) io.Reader {
+	e, err := enc.textEncoding()
+	if err != nil {
+		return &errReader{err: err}
+	}
+	if enc == EncodingUTF8 {
+		// 输入中仍可能带有BOM
+		e = unicode.UTF8BOM
+	}
+	return transform.NewReader(r, e.NewDecoder())
+}
+
+// NewEncodeWriter returns a writer converting UTF-8 text to enc before writing it to w.
+// UTF-16 and EncodingUTF8BOM output starts with a byte order mark. Writing a character that enc
+// cannot represent fails. Close flushes the writer, it does not close w.
+//
+// 将UTF-8文本流式转换为enc编码后写入w
+// 示例:
+//
+//	w := file.NewEncodeWriter(f, file.EncodingUTF8BOM) // Excel能正确打开的CSV
+//	defer w.Close()
+func NewEncodeWriter(w io.Writer, enc Encoding) io.WriteCloser {
+	e, err := enc.textEncoding()
+	if err != nil {
+		return &errWriter{err: err}
+	}
+	return transform.NewWriter(w, e.NewEncoder())
+}
+
+// NewUTF8Reader detects the encoding of r from its first 4 KiB with DetectEncoding and returns
+// a reader converting it to UTF-8, together with the detected encoding.
+// It fails with ErrUnknownEncoding if the encoding cannot be detected.
+//
+// 自动检测编码并流式转换为UTF-8
+func NewUTF8Reader(r io.Reader) (io.Reader, Encoding, error) {
+	br := bufio.NewReaderSize(r, 4096)
+	head, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, EncodingUnknown, err
+	}
+	enc := DetectEncoding(head)
+	if enc == EncodingUnknown {
+		return nil, enc, ErrUnknownEncoding
+	}
+	return NewDecodeReader(br, enc), enc, nil
+}
+
+// ReadFileUTF8 reads the text file at path, detects its encoding with DetectEncoding and returns
+// its content converted to UTF-8, without byte order mark.
+//
+// 读取文本文件，自动检测编码并转换为UTF-8
+// 示例:
+//
+//	data, err := file.ReadFileUTF8("export.csv") // Excel导出的GBK或UTF-16文件
+func ReadFileUTF8(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	enc := DetectEncoding(data)
+	if enc == EncodingUnknown {
+		return nil, &os.PathError{Op: "read", Path: path, Err: ErrUnknownEncoding}
+	}
+	return DecodeBytes(data, enc)
+}
+
+// DecodeBytes converts data from enc to UTF-8, removing byte order marks.
+//
+// 将enc编码的数据转换为UTF-8
+func DecodeBytes(data []byte, enc Encoding) ([]byte, error) {
+	return io.ReadAll(NewDecodeReader(bytes.NewReader(data), enc))
+}
+
+// EncodeBytes converts the UTF-8 text data to enc.
+//
+// 将UTF-8文本转换为enc编码
+func EncodeBytes(data []byte, enc Encoding) ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewEncodeWriter(&buf, enc)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// errReader fails every read with err.
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// errWriter fails every write with err.
+type errWriter struct {
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func (w *errWriter) Close() error {
+	return w.err
+}
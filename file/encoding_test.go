@@ -0,0 +1,140 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const chineseCSV = "姓名,城市\n张三,北京\n李四,上海\n"
+
+// encode converts s to enc, failing the test on error.
+func encode(t *testing.T, s string, enc Encoding) []byte {
+	t.Helper()
+	data, err := EncodeBytes([]byte(s), enc)
+	if err != nil {
+		t.Fatalf("EncodeBytes(%v) error = %v", enc, err)
+	}
+	return data
+}
+
+func TestDetectEncoding(t *testing.T) {
+	for _, enc := range []Encoding{EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE, EncodingUTF16BE, EncodingGBK} {
+		if got := DetectEncoding(encode(t, chineseCSV, enc)); got != enc {
+			t.Errorf("DetectEncoding(%v) = %v", enc, got)
+		}
+	}
+
+	// 没有BOM的UTF-16
+	noBOM := encode(t, "name,city\nZhang,Beijing\n", EncodingUTF16LE)[2:]
+	if got := DetectEncoding(noBOM); got != EncodingUTF16LE {
+		t.Errorf("DetectEncoding(UTF-16LE without BOM) = %v", got)
+	}
+
+	// GBK无法表示的字符在GB18030中使用四字节编码
+	gb18030 := encode(t, "emoji 😀 和中文", EncodingGB18030)
+	if got := DetectEncoding(gb18030); got != EncodingGB18030 {
+		t.Errorf("DetectEncoding(GB18030) = %v", got)
+	}
+
+	// 截断在多字节字符中间的前缀
+	utf8Data := []byte(chineseCSV)
+	if got := DetectEncoding(utf8Data[:len("姓名,城")+1]); got != EncodingUTF8 {
+		t.Errorf("DetectEncoding(cut UTF-8) = %v", got)
+	}
+	gbk := encode(t, chineseCSV, EncodingGBK)
+	if got := DetectEncoding(gbk[:5]); got != EncodingGBK {
+		t.Errorf("DetectEncoding(cut GBK) = %v", got)
+	}
+
+	tests := map[string]Encoding{
+		"":                         EncodingUTF8,
+		"plain ascii":              EncodingUTF8,
+		"\x00\x01\x02\xff\xfe\x7f": EncodingUnknown,
+		"abc\xff":                  EncodingUnknown,
+	}
+	for in, want := range tests {
+		if got := DetectEncoding([]byte(in)); got != want {
+			t.Errorf("DetectEncoding(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestEncodingConversion(t *testing.T) {
+	for _, enc := range []Encoding{EncodingUTF8, EncodingUTF8BOM, EncodingUTF16LE, EncodingUTF16BE, EncodingGBK, EncodingGB18030} {
+		data := encode(t, chineseCSV, enc)
+		got, err := DecodeBytes(data, enc)
+		if err != nil || string(got) != chineseCSV {
+			t.Errorf("DecodeBytes(%v) = %q, %v", enc, got, err)
+		}
+
+		r, detected, err := NewUTF8Reader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewUTF8Reader(%v) error = %v", enc, err)
+		}
+		got, _ = io.ReadAll(r)
+		if string(got) != chineseCSV || (detected != enc && enc != EncodingGB18030) {
+			t.Errorf("NewUTF8Reader(%v) = %q, detected %v", enc, got, detected)
+		}
+	}
+
+	if !bytes.HasPrefix(encode(t, "a", EncodingUTF8BOM), []byte("\xef\xbb\xbf")) {
+		t.Error("EncodingUTF8BOM does not write a BOM")
+	}
+	if got := encode(t, "a", EncodingUTF16LE); string(got) != "\xff\xfea\x00" {
+		t.Errorf("EncodeBytes(UTF-16LE) = %q", got)
+	}
+	if _, err := EncodeBytes([]byte("😀"), EncodingGBK); err == nil {
+		t.Error("EncodeBytes(GBK) of an emoji error = nil")
+	}
+	if _, err := DecodeBytes([]byte("a"), EncodingUnknown); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("DecodeBytes(EncodingUnknown) error = %v", err)
+	}
+	if _, _, err := NewUTF8Reader(strings.NewReader("\x00\x01\x02\xff")); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("NewUTF8Reader(binary) error = %v", err)
+	}
+}
+
+func TestEncodeWriterStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewEncodeWriter(&buf, EncodingGBK)
+	// 多字节字符被拆分到两次写入中
+	data := []byte(chineseCSV)
+	for _, part := range [][]byte{data[:4], data[4:]} {
+		if _, err := w.Write(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := DecodeBytes(buf.Bytes(), EncodingGBK); string(got) != chineseCSV {
+		t.Errorf("NewEncodeWriter() round trip = %q", got)
+	}
+}
+
+func TestReadFileUTF8(t *testing.T) {
+	dir := t.TempDir()
+	for _, enc := range []Encoding{EncodingUTF8BOM, EncodingUTF16LE, EncodingGBK} {
+		p := filepath.Join(dir, enc.String()+".csv")
+		if err := os.WriteFile(p, encode(t, chineseCSV, enc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFileUTF8(p)
+		if err != nil || string(got) != chineseCSV {
+			t.Errorf("ReadFileUTF8(%v) = %q, %v", enc, got, err)
+		}
+	}
+
+	p := filepath.Join(dir, "binary")
+	if err := os.WriteFile(p, []byte{0, 1, 2, 0xff}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFileUTF8(p); !errors.Is(err, ErrUnknownEncoding) {
+		t.Errorf("ReadFileUTF8(binary) error = %v", err)
+	}
+}
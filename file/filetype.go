@@ -0,0 +1,366 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLen is the number of bytes DetectType looks at.
+const sniffLen = 8192
+
+// FileType is a file format identified by DetectType.
+//
+// 根据文件内容识别出的文件类型
+type FileType struct {
+	// MIME is the media type, e.g. "image/png". Unknown binary data is "application/octet-stream".
+	MIME string
+	// Ext is the canonical extension with a leading dot, e.g. ".png", or "" if the format has none.
+	Ext string
+}
+
+// Common file types returned by DetectType.
+var (
+	TypeUnknown = FileType{"application/octet-stream", ""}
+	TypeText    = FileType{"text/plain; charset=utf-8", ".txt"}
+)
+
+// String returns the MIME type.
+func (t FileType) String() string {
+	return t.MIME
+}
+
+// IsText reports whether t is a text format, including JSON, XML, HTML and SVG.
+func (t FileType) IsText() bool {
+	switch {
+	case strings.HasPrefix(t.MIME, "text/"):
+		return true
+	case t.MIME == "application/json", t.MIME == "application/xml", t.MIME == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// Is reports whether the MIME type of t, without parameters, is one of mimes.
+// A MIME type ending in "/*" matches the whole category, e.g. "image/*".
+//
+// 判断类型是否属于给定的MIME类型之一
+// 示例:
+//
+//	if !t.Is("image/*", "application/pdf") { ... }
+func (t FileType) Is(mimes ...string) bool {
+	base, _, _ := strings.Cut(t.MIME, ";")
+	for _, m := range mimes {
+		if m == base || (strings.HasSuffix(m, "/*") && strings.HasPrefix(base, m[:len(m)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectType identifies the format of the file at path from its first bytes, ignoring its extension.
+// See DetectTypeBytes.
+//
+// 根据文件头的魔数识别文件类型，不依赖扩展名
+// 示例:
+//
+//	t, err := file.DetectType("upload.bin")
+//	fmt.Println(t.MIME, t.Ext) // image/png .png
+func DetectType(path string) (FileType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TypeUnknown, err
+	}
+	defer f.Close()
+	return DetectTypeReader(f)
+}
+
+// DetectTypeReader identifies the format of the data read from r, consuming up to 8 KiB of it.
+// To read the whole data afterwards, seek r back or read from io.MultiReader of the consumed bytes and r.
+//
+// 从io.Reader识别文件类型，最多读取8KiB
+func DetectTypeReader(r io.Reader) (FileType, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return TypeUnknown, err
+	}
+	return DetectTypeBytes(buf[:n]), nil
+}
+
+// DetectTypeBytes identifies the format of data from magic bytes. It knows the common image, audio,
+// video, document, Office, archive, font and executable formats, then tells text, including JSON,
+// XML, HTML and SVG, from binary data. Text in other encodings than UTF-8, e.g. GBK, is reported with
+// its charset, like "text/plain; charset=gbk". Only the first 8 KiB of data are considered.
+//
+// 根据魔数识别数据的类型
+func DetectTypeBytes(data []byte) FileType {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+	for _, s := range signatures {
+		if s.match(data) {
+			if s.refine != nil {
+				return s.refine(data)
+			}
+			return s.typ
+		}
+	}
+	return detectText(data)
+}
+
+// signature recognises a format by its magic bytes.
+type signature struct {
+	typ   FileType
+	match func(b []byte) bool
+	// refine, if set, tells apart the formats sharing the magic bytes.
+	refine func(b []byte) FileType
+}
+
+// prefix matches data starting with magic.
+func prefix(magic string) func(b []byte) bool {
+	return func(b []byte) bool { return bytes.HasPrefix(b, []byte(magic)) }
+}
+
+// at matches data containing magic at offset.
+func at(offset int, magic string) func(b []byte) bool {
+	return func(b []byte) bool {
+		return len(b) >= offset+len(magic) && string(b[offset:offset+len(magic)]) == magic
+	}
+}
+
+// riff matches RIFF containers of the given form type, such as "WAVE".
+func riff(form string) func(b []byte) bool {
+	return func(b []byte) bool { return prefix("RIFF")(b) && at(8, form)(b) }
+}
+
+var signatures = []signature{
+	// 图片
+	{typ: FileType{"image/jpeg", ".jpg"}, match: prefix("\xff\xd8\xff")},
+	{typ: FileType{"image/png", ".png"}, match: prefix("\x89PNG\r\n\x1a\n")},
+	{typ: FileType{"image/gif", ".gif"}, match: func(b []byte) bool { return prefix("GIF87a")(b) || prefix("GIF89a")(b) }},
+	{typ: FileType{"image/webp", ".webp"}, match: riff("WEBP")},
+	{typ: FileType{"image/bmp", ".bmp"}, match: func(b []byte) bool {
+		// BM之后的DIB头长度可以排除以BM开头的文本
+		return prefix("BM")(b) && len(b) >= 18 && strings.Contains("\x0c\x28\x34\x38\x6c\x7c", string(b[14])) && string(b[15:18]) == "\x00\x00\x00"
+	}},
+	{typ: FileType{"image/tiff", ".tif"}, match: func(b []byte) bool { return prefix("II*\x00")(b) || prefix("MM\x00*")(b) }},
+	{typ: FileType{"image/x-icon", ".ico"}, match: prefix("\x00\x00\x01\x00")},
+	{typ: FileType{"image/vnd.adobe.photoshop", ".psd"}, match: prefix("8BPS")},
+	{typ: FileType{"image/jxl", ".jxl"}, match: func(b []byte) bool { return prefix("\xff\x0a")(b) || prefix("\x00\x00\x00\x0cJXL \r\n\x87\n")(b) }},
+
+	// 文档
+	{typ: FileType{"application/pdf", ".pdf"}, match: prefix("%PDF-")},
+	{typ: FileType{"application/rtf", ".rtf"}, match: prefix(`{\rtf`)},
+	{typ: FileType{"application/postscript", ".ps"}, match: prefix("%!PS")},
+	{typ: FileType{"application/x-ole-storage", ""}, match: prefix("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), refine: refineOLE},
+	{typ: FileType{"application/vnd.sqlite3", ".sqlite"}, match: prefix("SQLite format 3\x00")},
+
+	// 压缩包
+	{typ: FileType{"application/zip", ".zip"}, match: func(b []byte) bool { return prefix("PK\x03\x04")(b) || prefix("PK\x05\x06")(b) }, refine: refineZip},
+	{typ: FileType{"application/gzip", ".gz"}, match: prefix("\x1f\x8b")},
+	{typ: FileType{"application/x-bzip2", ".bz2"}, match: func(b []byte) bool { return prefix("BZh")(b) && len(b) > 3 && b[3] >= '1' && b[3] <= '9' }},
+	{typ: FileType{"application/x-xz", ".xz"}, match: prefix("\xfd7zXZ\x00")},
+	{typ: FileType{"application/x-7z-compressed", ".7z"}, match: prefix("7z\xbc\xaf\x27\x1c")},
+	{typ: FileType{"application/vnd.rar", ".rar"}, match: prefix("Rar!\x1a\x07")},
+	{typ: FileType{"application/zstd", ".zst"}, match: prefix("\x28\xb5\x2f\xfd")},
+	{typ: FileType{"application/x-tar", ".tar"}, match: at(257, "ustar")},
+
+	// 音视频
+	{typ: FileType{"audio/wav", ".wav"}, match: riff("WAVE")},
+	{typ: FileType{"video/x-msvideo", ".avi"}, match: riff("AVI ")},
+	{typ: FileType{"audio/flac", ".flac"}, match: prefix("fLaC")},
+	{typ: FileType{"audio/ogg", ".ogg"}, match: prefix("OggS")},
+	{typ: FileType{"audio/midi", ".mid"}, match: prefix("MThd")},
+	{typ: FileType{"audio/mpeg", ".mp3"}, match: func(b []byte) bool {
+		return prefix("ID3")(b) || len(b) >= 2 && b[0] == 0xff && (b[1]&0xe6 == 0xe2 || b[1]&0xe6 == 0xe4)
+	}},
+	{typ: FileType{"audio/aac", ".aac"}, match: func(b []byte) bool { return len(b) >= 2 && b[0] == 0xff && b[1]&0xf6 == 0xf0 }},
+	{typ: FileType{"video/mp4", ".mp4"}, match: at(4, "ftyp"), refine: refineFtyp},
+	{typ: FileType{"video/x-matroska", ".mkv"}, match: prefix("\x1a\x45\xdf\xa3"), refine: refineMatroska},
+	{typ: FileType{"video/x-flv", ".flv"}, match: prefix("FLV\x01")},
+
+	// 字体
+	{typ: FileType{"font/woff", ".woff"}, match: prefix("wOFF")},
+	{typ: FileType{"font/woff2", ".woff2"}, match: prefix("wOF2")},
+	{typ: FileType{"font/ttf", ".ttf"}, match: prefix("\x00\x01\x00\x00\x00")},
+	{typ: FileType{"font/otf", ".otf"}, match: prefix("OTTO")},
+
+	// 可执行文件
+	{typ: FileType{"application/x-elf", ""}, match: prefix("\x7fELF")},
+	{typ: FileType{"application/vnd.microsoft.portable-executable", ".exe"}, match: func(b []byte) bool { return prefix("MZ")(b) && len(b) >= 64 }},
+	{typ: FileType{"application/x-mach-binary", ""}, match: func(b []byte) bool {
+		return prefix("\xfe\xed\xfa\xce")(b) || prefix("\xfe\xed\xfa\xcf")(b) || prefix("\xce\xfa\xed\xfe")(b) || prefix("\xcf\xfa\xed\xfe")(b)
+	}},
+	{typ: FileType{"application/wasm", ".wasm"}, match: prefix("\x00asm")},
+}
+
+// refineZip recognises the zip based formats from the names of their first entries.
+func refineZip(b []byte) FileType {
+	// ODF和EPUB的第一个条目是未压缩的mimetype文件
+	if at(30, "mimetype")(b) {
+		content := b[38:min(len(b), 38+80)]
+		switch {
+		case bytes.HasPrefix(content, []byte("application/epub+zip")):
+			return FileType{"application/epub+zip", ".epub"}
+		case bytes.HasPrefix(content, []byte("application/vnd.oasis.opendocument.text")):
+			return FileType{"application/vnd.oasis.opendocument.text", ".odt"}
+		case bytes.HasPrefix(content, []byte("application/vnd.oasis.opendocument.spreadsheet")):
+			return FileType{"application/vnd.oasis.opendocument.spreadsheet", ".ods"}
+		case bytes.HasPrefix(content, []byte("application/vnd.oasis.opendocument.presentation")):
+			return FileType{"application/vnd.oasis.opendocument.presentation", ".odp"}
+		}
+	}
+	switch {
+	case bytes.Contains(b, []byte("word/")):
+		return FileType{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"}
+	case bytes.Contains(b, []byte("xl/")):
+		return FileType{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"}
+	case bytes.Contains(b, []byte("ppt/")):
+		return FileType{"application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"}
+	case bytes.Contains(b, []byte("AndroidManifest.xml")):
+		return FileType{"application/vnd.android.package-archive", ".apk"}
+	case bytes.Contains(b, []byte("META-INF/MANIFEST.MF")):
+		return FileType{"application/java-archive", ".jar"}
+	}
+	return FileType{"application/zip", ".zip"}
+}
+
+// refineOLE recognises the legacy Office formats from the stream names of the compound file,
+// when its directory is within the sniffed bytes.
+func refineOLE(b []byte) FileType {
+	utf16 := func(s string) []byte {
+		out := make([]byte, 0, 2*len(s))
+		for i := 0; i < len(s); i++ {
+			out = append(out, s[i], 0)
+		}
+		return out
+	}
+	switch {
+	case bytes.Contains(b, utf16("WordDocument")):
+		return FileType{"application/msword", ".doc"}
+	case bytes.Contains(b, utf16("Workbook")), bytes.Contains(b, utf16("Book")):
+		return FileType{"application/vnd.ms-excel", ".xls"}
+	case bytes.Contains(b, utf16("PowerPoint Document")):
+		return FileType{"application/vnd.ms-powerpoint", ".ppt"}
+	}
+	return FileType{"application/x-ole-storage", ""}
+}
+
+// refineFtyp tells apart the ISO base media formats (MP4, QuickTime, HEIF, ...) from the major brand.
+func refineFtyp(b []byte) FileType {
+	if len(b) < 12 {
+		return FileType{"video/mp4", ".mp4"}
+	}
+	switch brand := string(b[8:12]); {
+	case brand == "qt  ":
+		return FileType{"video/quicktime", ".mov"}
+	case brand == "M4A " || brand == "M4B ":
+		return FileType{"audio/mp4", ".m4a"}
+	case brand == "M4V " || brand == "M4VH" || brand == "M4VP":
+		return FileType{"video/x-m4v", ".m4v"}
+	case brand == "avif" || brand == "avis":
+		return FileType{"image/avif", ".avif"}
+	case brand == "heic" || brand == "heix" || brand == "heim" || brand == "heis" || brand == "mif1" || brand == "msf1":
+		return FileType{"image/heic", ".heic"}
+	case strings.HasPrefix(brand, "3g2"):
+		return FileType{"video/3gpp2", ".3g2"}
+	case strings.HasPrefix(brand, "3gp"):
+		return FileType{"video/3gpp", ".3gp"}
+	case brand == "crx ":
+		return FileType{"image/x-canon-cr3", ".cr3"}
+	}
+	return FileType{"video/mp4", ".mp4"}
+}
+
+// refineMatroska tells WebM from other Matroska files by the DocType in the EBML header.
+func refineMatroska(b []byte) FileType {
+	if bytes.Contains(b[:min(len(b), 64)], []byte("webm")) {
+		return FileType{"video/webm", ".webm"}
+	}
+	return FileType{"video/x-matroska", ".mkv"}
+}
+
+// detectText classifies data without magic bytes as text or binary. Text that is not UTF-8 is
+// identified with DetectEncoding, e.g. GBK, and reported with its charset.
+func detectText(b []byte) FileType {
+	if len(b) == 0 {
+		return TypeText
+	}
+	charset := "utf-8"
+	switch {
+	case bytes.HasPrefix(b, []byte("\xef\xbb\xbf")):
+		b = b[3:]
+	case bytes.HasPrefix(b, []byte("\xff\xfe")):
+		// UTF-16文本，内容格式无法进一步判断
+		return FileType{"text/plain; charset=utf-16le", ".txt"}
+	case bytes.HasPrefix(b, []byte("\xfe\xff")):
+		return FileType{"text/plain; charset=utf-16be", ".txt"}
+	}
+	if !looksLikeText(b) {
+		if hasControlBytes(b) {
+			return TypeUnknown
+		}
+		switch DetectEncoding(b) {
+		case EncodingGBK:
+			charset = "gbk"
+		case EncodingGB18030:
+			charset = "gb18030"
+		default:
+			// 没有控制字符的其他8位编码，按Latin-1处理
+			charset = "iso-8859-1"
+		}
+	}
+
+	trimmed := bytes.TrimLeft(b, " \t\r\n")
+	lower := bytes.ToLower(trimmed[:min(len(trimmed), 512)])
+	switch {
+	case bytes.HasPrefix(lower, []byte("<!doctype html")), bytes.HasPrefix(lower, []byte("<html")):
+		return FileType{"text/html; charset=" + charset, ".html"}
+	case bytes.HasPrefix(lower, []byte("<svg")):
+		return FileType{"image/svg+xml", ".svg"}
+	case bytes.HasPrefix(lower, []byte("<?xml")):
+		if bytes.Contains(bytes.ToLower(trimmed), []byte("<svg")) {
+			return FileType{"image/svg+xml", ".svg"}
+		}
+		return FileType{"application/xml", ".xml"}
+	case charset == "utf-8" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && len(b) < sniffLen && json.Valid(trimmed):
+		return FileType{"application/json", ".json"}
+	case bytes.HasPrefix(trimmed, []byte("#!")):
+		return FileType{"text/x-shellscript", ".sh"}
+	}
+	if charset != "utf-8" {
+		return FileType{"text/plain; charset=" + charset, ".txt"}
+	}
+	return TypeText
+}
+
+// hasControlBytes reports whether b contains bytes that do not occur in text,
+// the same as those http.DetectContentType takes as binary.
+func hasControlBytes(b []byte) bool {
+	for _, c := range b {
+		if c <= 0x08 || c == 0x0b || (0x0e <= c && c <= 0x1a) || (0x1c <= c && c <= 0x1f) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeText reports whether b is UTF-8 without control characters other than whitespace and escape.
+// A rune cut at the end of b is allowed.
+func looksLikeText(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return len(b) < utf8.UTFMax && !utf8.FullRune(b)
+		}
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != 0x1b {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectTypeBytes(t *testing.T) {
+	pad := func(s string) []byte { return append([]byte(s), make([]byte, 64)...) }
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", pad("\xff\xd8\xff\xe0\x00\x10JFIF"), ".jpg"},
+		{"gif", pad("GIF89a"), ".gif"},
+		{"webp", pad("RIFF\x00\x00\x00\x00WEBPVP8 "), ".webp"},
+		{"wav", pad("RIFF\x00\x00\x00\x00WAVEfmt "), ".wav"},
+		{"bmp", pad("BM\x00\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00\x28\x00\x00\x00"), ".bmp"},
+		{"pdf", pad("%PDF-1.7\n"), ".pdf"},
+		{"7z", pad("7z\xbc\xaf\x27\x1c"), ".7z"},
+		{"mp3", pad("ID3\x03\x00"), ".mp3"},
+		{"mp3 frame", pad("\xff\xfb\x90\x64"), ".mp3"},
+		{"mp4", pad("\x00\x00\x00\x18ftypisom"), ".mp4"},
+		{"mov", pad("\x00\x00\x00\x14ftypqt  "), ".mov"},
+		{"heic", pad("\x00\x00\x00\x18ftypheic"), ".heic"},
+		{"webm", pad("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm"), ".webm"},
+		{"elf", pad("\x7fELF\x02\x01\x01"), ""},
+		{"exe", pad("MZ\x90\x00"), ".exe"},
+		{"sqlite", pad("SQLite format 3\x00"), ".sqlite"},
+		{"doc", pad("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1" + "W\x00o\x00r\x00d\x00D\x00o\x00c\x00u\x00m\x00e\x00n\x00t\x00"), ".doc"},
+		{"tar", append(make([]byte, 257), pad("ustar\x0000")...), ".tar"},
+		{"text", []byte("hello, 世界\n"), ".txt"},
+		{"text starting with BM", []byte("BMW is a car maker, mentioned in this note.\n"), ".txt"},
+		{"empty", nil, ".txt"},
+		{"json", []byte(` {"a": [1, 2]}`), ".json"},
+		{"truncated json", []byte(`{"a": [1, 2`), ".txt"},
+		{"html", []byte("<!DOCTYPE html><html></html>"), ".html"},
+		{"xml", []byte(`<?xml version="1.0"?><root/>`), ".xml"},
+		{"svg", []byte(`<?xml version="1.0"?>` + "\n" + `<svg xmlns="http://www.w3.org/2000/svg"/>`), ".svg"},
+		{"script", []byte("#!/bin/sh\necho hi\n"), ".sh"},
+		{"utf8 bom", []byte("\xef\xbb\xbf{}"), ".json"},
+		{"binary", []byte("\x00\x01\x02\x03garbage"), ""},
+		{"gbk", encode(t, "中文文本，不是二进制文件。\n第二行", EncodingGBK), ".txt"},
+		{"latin-1", []byte("caf\xe9 cr\xe8me br\xfbl\xe9e\n"), ".txt"},
+		{"utf-16", []byte("\xff\xfeh\x00i\x00"), ".txt"},
+	}
+	for _, tt := range tests {
+		if got := DetectTypeBytes(tt.data); got.Ext != tt.want {
+			t.Errorf("DetectTypeBytes(%s) = %v %q, want extension %q", tt.name, got, got.Ext, tt.want)
+		}
+	}
+
+	if got := DetectTypeBytes(nil); got != TypeText || !got.IsText() {
+		t.Errorf("DetectTypeBytes(nil) = %v", got)
+	}
+	if got := DetectTypeBytes([]byte{0, 1, 2}); got != TypeUnknown || got.IsText() {
+		t.Errorf("DetectTypeBytes(binary) = %v", got)
+	}
+	gbk := encode(t, "<html><body>中文</body></html>", EncodingGBK)
+	if got := DetectTypeBytes(gbk); got.MIME != "text/html; charset=gbk" || !got.IsText() {
+		t.Errorf("DetectTypeBytes(gbk html) = %v", got)
+	}
+	if got := DetectTypeBytes(encode(t, "中文", EncodingGBK)); got.MIME != "text/plain; charset=gbk" {
+		t.Errorf("DetectTypeBytes(gbk) = %v", got)
+	}
+}
+
+func TestDetectType(t *testing.T) {
+	dir := t.TempDir()
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	var docx bytes.Buffer
+	zw := zip.NewWriter(&docx)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte("<xml/>"))
+	}
+	zw.Close()
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(strings.Repeat("x", 100)))
+	gw.Close()
+
+	files := map[string][]byte{
+		"image.dat": img.Bytes(),
+		"doc.zip":   docx.Bytes(),
+		"data.txt":  gz.Bytes(),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := makeTree(t, map[string]string{"a.txt": "a"})
+	if err := Zip(context.Background(), src, filepath.Join(dir, "plain.bin"), ArchiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]FileType{
+		"image.dat": {"image/png", ".png"},
+		"doc.zip":   {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+		"data.txt":  {"application/gzip", ".gz"},
+		"plain.bin": {"application/zip", ".zip"},
+	}
+	for name, w := range want {
+		got, err := DetectType(filepath.Join(dir, name))
+		if err != nil || got != w {
+			t.Errorf("DetectType(%s) = %v, %v, want %v", name, got, err, w)
+		}
+	}
+
+	if got, _ := DetectType(filepath.Join(dir, "image.dat")); !got.Is("image/*") || got.Is("image/jpeg", "text/*") {
+		t.Errorf("FileType.Is() gave a wrong answer for %v", got)
+	}
+	if !TypeText.Is("text/plain") {
+		t.Error("FileType.Is() does not ignore MIME parameters")
+	}
+	if _, err := DetectType(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("DetectType(missing) error = %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// FollowStart selects where Follow starts reading.
+//
+// Follow开始读取的位置
+type FollowStart int
+
+const (
+	// FromEnd only reports lines appended after Follow is called.
+	FromEnd FollowStart = iota
+	// FromStart reports the whole file first.
+	FromStart
+	// FromOffset starts at FollowOptions.Offset, e.g. the Offset of the last line handled before a restart.
+	// If the file is shorter than the offset, it was truncated or replaced and is read from the start.
+	FromOffset
+)
+
+// FollowOptions configures FollowWithOptions.
+//
+// Follow的配置项
+type FollowOptions struct {
+	Start FollowStart
+	// Offset is the byte offset used with FromOffset.
+	Offset int64
+	// Interval is how often the file is checked for new data, truncation and rotation. Defaults to 250ms.
+	Interval time.Duration
+	// OnError is called when the file cannot be read. Following goes on, so transient errors are harmless.
+	OnError func(err error)
+}
+
+// Line is a line read by Follow.
+//
+// Follow读取到的一行
+type Line struct {
+	// Text is the line without its line ending.
+	Text string
+	// Offset is the byte offset just after the line in the current file.
+	// Store it and pass it back with FromOffset to resume without losing or repeating lines.
+	Offset int64
+}
+
+// Follow sends the lines appended to the file at path until ctx is done, like `tail -F`.
+// It keeps following across truncation and rotation: when the file is truncated it is read again from
+// the start, and when path is replaced by a new file (a different inode) the old file is read to its end
+// before the new one is opened. The file does not need to exist yet. Only complete lines are sent,
+// except for an unterminated last line of a rotated file.
+//
+// 类似tail -F跟踪文件新增的行，能处理文件截断和日志轮转
+// 示例:
+//
+//	lines, err := file.Follow(ctx, "/var/log/app.log")
+//	for line := range lines {
+//		handle(line.Text)
+//		saveOffset(line.Offset)
+//	}
+func Follow(ctx context.Context, path string) (<-chan Line, error) {
+	return FollowWithOptions(ctx, path, FollowOptions{})
+}
+
+// FollowWithOptions is like Follow with more control over the starting position and polling.
+//
+// 同Follow，可以指定开始位置等配置
+func FollowWithOptions(ctx context.Context, path string, opts FollowOptions) (<-chan Line, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 250 * time.Millisecond
+	}
+	fl := &follower{path: path, opts: opts, buf: make([]byte, 32*1024)}
+	if err := fl.open(opts.Start, opts.Offset); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	lines := make(chan Line, 64)
+	go func() {
+		defer close(lines)
+		defer fl.close()
+		for {
+			if !fl.poll(ctx, lines) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(opts.Interval):
+			}
+		}
+	}()
+	return lines, nil
+}
+
+type follower struct {
+	path    string
+	opts    FollowOptions
+	f       *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte
+	buf     []byte
+}
+
+func (fl *follower) error(err error) {
+	if fl.opts.OnError != nil {
+		fl.opts.OnError(err)
+	}
+}
+
+// open opens the file at path and seeks to the starting position.
+func (fl *follower) open(start FollowStart, offset int64) error {
+	f, err := os.Open(fl.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	switch {
+	case start == FromEnd:
+		offset = info.Size()
+	case start == FromStart || offset > info.Size() || offset < 0:
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return err
+	}
+	fl.f, fl.info, fl.offset, fl.partial = f, info, offset, fl.partial[:0]
+	return nil
+}
+
+func (fl *follower) close() {
+	if fl.f != nil {
+		_ = fl.f.Close()
+		fl.f = nil
+	}
+}
+
+// poll sends everything readable, then handles truncation and rotation. It returns false when ctx is done.
+func (fl *follower) poll(ctx context.Context, lines chan<- Line) bool {
+	if fl.f == nil {
+		// 文件还不存在或已被删除，等待新文件出现后从头读取
+		if err := fl.open(FromStart, 0); err != nil {
+			if !os.IsNotExist(err) {
+				fl.error(err)
+			}
+			return true
+		}
+	}
+	if !fl.read(ctx, lines) {
+		return false
+	}
+
+	info, err := os.Stat(fl.path)
+	switch {
+	case os.IsNotExist(err) || (err == nil && !os.SameFile(fl.info, info)):
+		// 文件被轮转，旧文件已读完，发送未结束的最后一行后切换到新文件
+		if len(fl.partial) > 0 && !fl.send(ctx, lines, fl.partial, fl.offset) {
+			return false
+		}
+		fl.close()
+		if err == nil {
+			return fl.poll(ctx, lines)
+		}
+	case err != nil:
+		fl.error(err)
+	case info.Size() < fl.offset:
+		// 文件被截断，从头读取
+		if _, err := fl.f.Seek(0, io.SeekStart); err != nil {
+			fl.error(err)
+			return true
+		}
+		fl.offset, fl.partial = 0, fl.partial[:0]
+		return fl.read(ctx, lines)
+	}
+	return true
+}
+
+// read sends the complete lines available in the current file.
+func (fl *follower) read(ctx context.Context, lines chan<- Line) bool {
+	for {
+		n, err := fl.f.Read(fl.buf)
+		data := fl.buf[:n]
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				fl.partial = append(fl.partial, data...)
+				fl.offset += int64(len(data))
+				break
+			}
+			fl.partial = append(fl.partial, data[:i]...)
+			fl.offset += int64(i + 1)
+			if !fl.send(ctx, lines, fl.partial, fl.offset) {
+				return false
+			}
+			fl.partial = fl.partial[:0]
+			data = data[i+1:]
+		}
+		if err == io.EOF || (err == nil && n == 0) {
+			return true
+		}
+		if err != nil {
+			fl.error(err)
+			return true
+		}
+	}
+}
+
+func (fl *follower) send(ctx context.Context, lines chan<- Line, text []byte, offset int64) bool {
+	line := Line{Text: string(bytes.TrimSuffix(text, []byte("\r"))), Offset: offset}
+	select {
+	case lines <- line:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receive reads n lines or fails after a timeout.
+func receive(t *testing.T, lines <-chan Line, n int) []Line {
+	t.Helper()
+	var got []Line
+	for len(got) < n {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				t.Fatalf("channel closed after %v", got)
+			}
+			got = append(got, l)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout after %v", got)
+		}
+	}
+	return got
+}
+
+func texts(lines []Line) []string {
+	result := make([]string, len(lines))
+	for i, l := range lines {
+		result[i] = l.Text
+	}
+	return result
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "old\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := FollowWithOptions(ctx, path, FollowOptions{Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appendFile(t, path, "one\r\ntw")
+	appendFile(t, path, "o\n")
+	got := receive(t, lines, 2)
+	if want := []Line{{"one", 9}, {"two", 13}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("append: got %v, want %v", got, want)
+	}
+
+	// 截断
+	writeFile(t, path, "")
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "after truncate\n")
+	if got := texts(receive(t, lines, 1)); got[0] != "after truncate" {
+		t.Errorf("truncate: got %q", got)
+	}
+
+	// 轮转
+	appendFile(t, path, "last old")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, "new file\n")
+	if got := texts(receive(t, lines, 2)); !reflect.DeepEqual(got, []string{"last old", "new file"}) {
+		t.Errorf("rotate: got %q", got)
+	}
+
+	cancel()
+	for range lines {
+	}
+}
+
+func TestFollowStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	writeFile(t, path, "a\nb\nc\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lines, err := FollowWithOptions(ctx, path, FollowOptions{Start: FromStart, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := receive(t, lines, 3)
+	if !reflect.DeepEqual(texts(got), []string{"a", "b", "c"}) {
+		t.Errorf("from start: got %v", got)
+	}
+
+	lines, err = FollowWithOptions(ctx, path, FollowOptions{Start: FromOffset, Offset: got[0].Offset, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(receive(t, lines, 2)); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("from offset: got %q", got)
+	}
+
+	lines, err = FollowWithOptions(ctx, path, FollowOptions{Start: FromOffset, Offset: 100, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := texts(receive(t, lines, 3)); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("offset past the end: got %q", got)
+	}
+}
+
+func TestFollowMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "later.log")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := FollowWithOptions(ctx, path, FollowOptions{Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	writeFile(t, path, "hello\n")
+	if got := texts(receive(t, lines, 1)); got[0] != "hello" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,585 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// FS is a writable filesystem. Like io/fs.FS, names are slash separated and unrooted, e.g. "dir/file.txt".
+// Stat and ReadDir follow symlinks, Lstat does not.
+//
+// 可写的文件系统接口，在io/fs.FS的基础上增加了写操作
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	// Lstat is like Stat but does not follow a final symlink.
+	Lstat(name string) (fs.FileInfo, error)
+	// Readlink returns the target of a symlink.
+	Readlink(name string) (string, error)
+	// OpenFile opens a file with os.OpenFile flags.
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Mkdir(name string, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	// Symlink creates newname as a symlink to oldname. Filesystems without symlinks return errors.ErrUnsupported.
+	Symlink(oldname, newname string) error
+}
+
+// File is an open file of an FS.
+//
+// FS中打开的文件
+type File interface {
+	fs.File
+	io.Writer
+}
+
+// dirFS is the FS of a directory of the host filesystem.
+// The zero root is used internally for the path based helpers: names are host paths and are not validated.
+type dirFS struct {
+	root string
+	// jail refuses names that resolve outside of root through symlinks.
+	jail bool
+}
+
+// hostFS takes host paths as names, it backs the path based helpers such as Walk and Copy.
+var hostFS = &dirFS{}
+
+// DirFS returns the FS of the directory dir. Like os.DirFS, symlinks are followed wherever they point to;
+// use Sub to confine access to dir.
+//
+// 返回目录对应的可写文件系统
+// 示例:
+//
+//	fsys := file.DirFS("/srv/data")
+//	err := file.WriteAtomicFS(fsys, "state.json", data, 0o644)
+func DirFS(dir string) FS {
+	if dir == "" {
+		dir = "."
+	}
+	return &dirFS{root: dir}
+}
+
+// resolve validates name and returns the host path for it. followLast tells whether the
+// operation follows a final symlink, which the jail must then check as well.
+func (d *dirFS) resolve(op, name string, followLast bool) (string, error) {
+	if d.root == "" {
+		return name, nil
+	}
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	p := filepath.Join(d.root, filepath.FromSlash(name))
+	if d.jail {
+		if err := d.confine(p, followLast); err != nil {
+			return "", &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	}
+	return p, nil
+}
+
+func (d *dirFS) confine(p string, followLast bool) error {
+	root, err := filepath.EvalSymlinks(d.root)
+	if err != nil {
+		return err
+	}
+	target := p
+	if !followLast {
+		target = filepath.Dir(p)
+	}
+	real, err := evalExisting(target, 0)
+	if err != nil {
+		return err
+	}
+	if !isWithin(root, real) {
+		return ErrPathEscapes
+	}
+	return nil
+}
+
+// evalExisting resolves the symlinks of p like filepath.EvalSymlinks, but also for paths that do not
+// fully exist yet, including dangling symlinks, so that creating a file cannot escape through them.
+func evalExisting(p string, depth int) (string, error) {
+	if depth > 40 {
+		return "", errors.New("too many levels of symbolic links")
+	}
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		return real, nil
+	}
+	info, err := os.Lstat(p)
+	switch {
+	case err == nil && info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(p)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(p), target)
+		}
+		return evalExisting(target, depth+1)
+	case err != nil && !os.IsNotExist(err):
+		return "", err
+	}
+	parent := filepath.Dir(p)
+	if parent == p {
+		return p, nil
+	}
+	real, err := evalExisting(parent, depth)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(p)), nil
+}
+
+// realPath resolves the symlinks of name, used by Walker to detect cycles.
+func (d *dirFS) realPath(name string) (string, error) {
+	p, err := d.resolve("realpath", name, true)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(p)
+}
+
+func (d *dirFS) Open(name string) (fs.File, error) {
+	p, err := d.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (d *dirFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := d.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+func (d *dirFS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := d.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(p)
+}
+
+func (d *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := d.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(p)
+}
+
+func (d *dirFS) Readlink(name string) (string, error) {
+	p, err := d.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(p)
+}
+
+func (d *dirFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	p, err := d.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (d *dirFS) Mkdir(name string, perm fs.FileMode) error {
+	p, err := d.resolve("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(p, perm)
+}
+
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	p, err := d.resolve("mkdir", name, true)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(p, perm)
+}
+
+func (d *dirFS) Remove(name string) error {
+	p, err := d.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+func (d *dirFS) RemoveAll(name string) error {
+	p, err := d.resolve("removeall", name, false)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(p)
+}
+
+func (d *dirFS) Rename(oldname, newname string) error {
+	oldPath, err := d.resolve("rename", oldname, false)
+	if err != nil {
+		return err
+	}
+	newPath, err := d.resolve("rename", newname, false)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func (d *dirFS) Chmod(name string, mode fs.FileMode) error {
+	p, err := d.resolve("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(p, mode)
+}
+
+func (d *dirFS) Chtimes(name string, atime, mtime time.Time) error {
+	p, err := d.resolve("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(p, atime, mtime)
+}
+
+func (d *dirFS) Symlink(oldname, newname string) error {
+	p, err := d.resolve("symlink", newname, false)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(oldname, p)
+}
+
+// Sub returns the FS of the subdirectory dir of fsys. Names cannot contain "..", so they stay inside dir.
+// For a DirFS, Sub is a jail: names that resolve outside of dir through symlinks are refused.
+//
+// 返回子目录对应的文件系统，访问不会超出该目录
+func Sub(fsys FS, dir string) (FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return fsys, nil
+	}
+	if d, ok := fsys.(*dirFS); ok && d.root != "" {
+		return &dirFS{root: filepath.Join(d.root, filepath.FromSlash(dir)), jail: true}, nil
+	}
+	return &subFS{fsys: fsys, dir: dir}, nil
+}
+
+type subFS struct {
+	fsys FS
+	dir  string
+}
+
+// full returns the name in the parent filesystem.
+func (s *subFS) full(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(s.dir, name), nil
+}
+
+// shorten rewrites the paths of errors from the parent filesystem relative to s.dir.
+func (s *subFS) shorten(err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		if rel, ok := trimDir(s.dir, pe.Path); ok {
+			return &fs.PathError{Op: pe.Op, Path: rel, Err: pe.Err}
+		}
+	}
+	return err
+}
+
+func trimDir(dir, name string) (string, bool) {
+	if name == dir {
+		return ".", true
+	}
+	if len(name) > len(dir) && name[:len(dir)] == dir && name[len(dir)] == '/' {
+		return name[len(dir)+1:], true
+	}
+	return "", false
+}
+
+func (s *subFS) Open(name string) (fs.File, error) {
+	full, err := s.full("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fsys.Open(full)
+	return f, s.shorten(err)
+}
+
+func (s *subFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := s.full("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.fsys.Stat(full)
+	return info, s.shorten(err)
+}
+
+func (s *subFS) Lstat(name string) (fs.FileInfo, error) {
+	full, err := s.full("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.fsys.Lstat(full)
+	return info, s.shorten(err)
+}
+
+func (s *subFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := s.full("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.fsys.ReadDir(full)
+	return entries, s.shorten(err)
+}
+
+func (s *subFS) Readlink(name string) (string, error) {
+	full, err := s.full("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := s.fsys.Readlink(full)
+	return target, s.shorten(err)
+}
+
+func (s *subFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	full, err := s.full("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.fsys.OpenFile(full, flag, perm)
+	return f, s.shorten(err)
+}
+
+func (s *subFS) Mkdir(name string, perm fs.FileMode) error {
+	full, err := s.full("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Mkdir(full, perm))
+}
+
+func (s *subFS) MkdirAll(name string, perm fs.FileMode) error {
+	full, err := s.full("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.MkdirAll(full, perm))
+}
+
+func (s *subFS) Remove(name string) error {
+	full, err := s.full("remove", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Remove(full))
+}
+
+func (s *subFS) RemoveAll(name string) error {
+	full, err := s.full("removeall", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.RemoveAll(full))
+}
+
+func (s *subFS) Rename(oldname, newname string) error {
+	oldFull, err := s.full("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newFull, err := s.full("rename", newname)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Rename(oldFull, newFull))
+}
+
+func (s *subFS) Chmod(name string, mode fs.FileMode) error {
+	full, err := s.full("chmod", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Chmod(full, mode))
+}
+
+func (s *subFS) Chtimes(name string, atime, mtime time.Time) error {
+	full, err := s.full("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Chtimes(full, atime, mtime))
+}
+
+func (s *subFS) Symlink(oldname, newname string) error {
+	full, err := s.full("symlink", newname)
+	if err != nil {
+		return err
+	}
+	return s.shorten(s.fsys.Symlink(oldname, full))
+}
+
+// ReadOnly returns an FS that serves reads from fsys and fails every write with fs.ErrPermission.
+// It is handy to pass an embed.FS or fstest.MapFS where an FS is expected, or as the lower layer of an Overlay.
+//
+// 返回只读的文件系统，所有写操作返回fs.ErrPermission
+func ReadOnly(fsys fs.FS) FS {
+	return &readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func readOnlyError(op, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Open(name string) (fs.File, error)          { return r.fsys.Open(name) }
+func (r *readOnlyFS) Stat(name string) (fs.FileInfo, error)      { return fs.Stat(r.fsys, name) }
+func (r *readOnlyFS) Lstat(name string) (fs.FileInfo, error)     { return lstat(r.fsys, name) }
+func (r *readOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) { return fs.ReadDir(r.fsys, name) }
+func (r *readOnlyFS) Readlink(name string) (string, error)       { return readlink(r.fsys, name) }
+func (r *readOnlyFS) Mkdir(name string, perm fs.FileMode) error  { return readOnlyError("mkdir", name) }
+func (r *readOnlyFS) MkdirAll(name string, perm fs.FileMode) error {
+	return readOnlyError("mkdir", name)
+}
+func (r *readOnlyFS) Remove(name string) error                  { return readOnlyError("remove", name) }
+func (r *readOnlyFS) RemoveAll(name string) error               { return readOnlyError("removeall", name) }
+func (r *readOnlyFS) Rename(oldname, newname string) error      { return readOnlyError("rename", oldname) }
+func (r *readOnlyFS) Chmod(name string, mode fs.FileMode) error { return readOnlyError("chmod", name) }
+func (r *readOnlyFS) Symlink(oldname, newname string) error     { return readOnlyError("symlink", newname) }
+
+func (r *readOnlyFS) Chtimes(name string, atime, mtime time.Time) error {
+	return readOnlyError("chtimes", name)
+}
+
+func (r *readOnlyFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, readOnlyError("open", name)
+	}
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return readOnlyFile{f}, nil
+}
+
+type readOnlyFile struct {
+	fs.File
+}
+
+func (f readOnlyFile) Write(p []byte) (int, error) {
+	info, _ := f.Stat()
+	name := ""
+	if info != nil {
+		name = info.Name()
+	}
+	return 0, readOnlyError("write", name)
+}
+
+// lstat calls Lstat if fsys supports it and falls back to fs.Stat.
+func lstat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if l, ok := fsys.(interface {
+		Lstat(name string) (fs.FileInfo, error)
+	}); ok {
+		return l.Lstat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// readlink calls Readlink if fsys supports it.
+func readlink(fsys fs.FS, name string) (string, error) {
+	if l, ok := fsys.(interface {
+		Readlink(name string) (string, error)
+	}); ok {
+		return l.Readlink(name)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// joinPath joins path elements with the separator of fsys: host paths for the path based helpers,
+// slash separated names for every other filesystem.
+func joinPath(fsys fs.FS, elem ...string) string {
+	if fsys == fs.FS(hostFS) {
+		return filepath.Join(elem...)
+	}
+	return path.Join(elem...)
+}
+
+// dirPath returns the parent directory of name, see joinPath.
+func dirPath(fsys fs.FS, name string) string {
+	if fsys == fs.FS(hostFS) {
+		return filepath.Dir(name)
+	}
+	return path.Dir(name)
+}
+
+// sameFS reports whether a and b are the same filesystem, without panicking on incomparable types.
+func sameFS(a, b fs.FS) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta != nil && ta.Comparable() && a == b
+}
+
+// IsPathExistFS is IsPathExist for a file of fsys.
+//
+// 判断文件系统中的文件是否存在
+func IsPathExistFS(fsys fs.FS, name string) (bool, error) {
+	_, err := fs.Stat(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// IsDirFS is IsDir for a file of fsys.
+//
+// 判断文件系统中的路径是否是目录
+func IsDirFS(fsys fs.FS, name string) (bool, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// WriteFileFS writes data to the file name of fsys, creating it with perm if needed, like os.WriteFile.
+//
+// 写入文件系统中的文件
+func WriteFileFS(fsys FS, name string, data []byte, perm fs.FileMode) error {
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,352 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fillFS writes files (with content) and directories (names ending in /) to fsys.
+func fillFS(t *testing.T, fsys FS, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if name[len(name)-1] == '/' {
+			if err := fsys.MkdirAll(name[:len(name)-1], 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := fsys.MkdirAll(dirPath(fsys, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteFileFS(fsys, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFS(t *testing.T, fsys fs.FS, name string) string {
+	t.Helper()
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS()
+	fillFS(t, m, map[string]string{
+		"a.txt":       "hello",
+		"dir/b.txt":   "b",
+		"dir/sub/c":   "c",
+		"empty/":      "",
+		"dir/sub/d.x": "d",
+	})
+	if err := fstest.TestFS(m, "a.txt", "dir/b.txt", "dir/sub/c", "dir/sub/d.x", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileFS(m, "missing/x", nil, 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("create without parent: got %v", err)
+	}
+	if _, err := m.OpenFile("a.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("O_EXCL: got %v", err)
+	}
+	f, err := m.OpenFile("a.txt", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(f, " world")
+	f.Close()
+	if got := readFS(t, m, "a.txt"); got != "hello world" {
+		t.Errorf("append: got %q", got)
+	}
+
+	if err := m.Remove("dir"); err == nil {
+		t.Error("removing a non-empty directory should fail")
+	}
+	if err := m.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, m, "moved/sub/c"); got != "c" {
+		t.Errorf("renamed tree: got %q", got)
+	}
+	if ok, _ := IsPathExistFS(m, "dir/b.txt"); ok {
+		t.Error("old name should be gone after rename")
+	}
+	if err := m.RemoveAll("moved"); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := m.ReadDir(".")
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.txt", "empty"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := m.Chtimes("a.txt", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Chmod("a.txt", 0o600); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := m.Stat("a.txt")
+	if !info.ModTime().Equal(mtime) || info.Mode() != 0o600 {
+		t.Errorf("got %v %v", info.ModTime(), info.Mode())
+	}
+	if err := m.Symlink("a.txt", "link"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("symlink: got %v", err)
+	}
+}
+
+func TestHelpersOnMemFS(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemFS()
+	fillFS(t, m, map[string]string{
+		"src/a.go":     "package a",
+		"src/b.txt":    "b",
+		"src/sub/c.go": "package c",
+	})
+
+	if ok, err := IsPathExistFS(m, "src/a.go"); !ok || err != nil {
+		t.Errorf("IsPathExistFS: %v, %v", ok, err)
+	}
+	if ok, err := IsDirFS(m, "src/sub"); !ok || err != nil {
+		t.Errorf("IsDirFS: %v, %v", ok, err)
+	}
+
+	entries, err := FindFS(m, "src", WalkOptions{Filters: []Filter{ByExt(".go")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rels(entries); !reflect.DeepEqual(got, []string{"a.go", "sub/c.go"}) {
+		t.Errorf("FindFS: got %v", got)
+	}
+	if entries[1].Path != "src/sub/c.go" {
+		t.Errorf("entry path: got %q", entries[1].Path)
+	}
+
+	if err := CopyDirFS(ctx, m, "src", m, "dst", CopyOptions{Exclude: []string{"*.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, m, "dst/sub/c.go"); got != "package c" {
+		t.Errorf("CopyDirFS: got %q", got)
+	}
+	if ok, _ := IsPathExistFS(m, "dst/b.txt"); ok {
+		t.Error("excluded file was copied")
+	}
+	if err := CopyDirFS(ctx, m, "src", m, "src/inner", CopyOptions{}); err == nil {
+		t.Error("copying into itself should fail")
+	}
+
+	// 复制到磁盘后哈希应一致
+	dir := t.TempDir()
+	if err := CopyDirFS(ctx, m, "dst", DirFS(dir), ".", CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	memSum, err := HashDirFS(m, "dst", SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diskSum, err := HashDir(dir, SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memSum != diskSum {
+		t.Errorf("HashDirFS %s != HashDir %s", memSum, diskSum)
+	}
+	if sum, _ := HashFS(m, "src/b.txt", MD5); sum != "92eb5ffee6ae2fec3ad71c777531578f" {
+		t.Errorf("HashFS: got %s", sum)
+	}
+
+	if err := WriteAtomicFS(m, "src/b.txt", []byte("new"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, m, "src/b.txt"); got != "new" {
+		t.Errorf("WriteAtomicFS: got %q", got)
+	}
+	if info, _ := m.Stat("src/b.txt"); info.Mode().Perm() != 0o644 {
+		t.Errorf("WriteAtomicFS should keep the mode, got %v", info.Mode())
+	}
+	left, _ := m.ReadDir("src")
+	if len(left) != 3 {
+		t.Errorf("temporary file left behind: %d entries", len(left))
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	root := makeTree(t, map[string]string{"a/b.txt": "b"})
+	fsys := DirFS(root)
+	if got := readFS(t, fsys, "a/b.txt"); got != "b" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := fsys.Open("../x"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("invalid name: got %v", err)
+	}
+	if err := WriteAtomicFS(fsys, "a/c.txt", []byte("c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, filepath.Join(root, "a", "c.txt"), "c")
+}
+
+func TestSubJail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	root := makeTree(t, map[string]string{
+		"jail/in.txt": "in",
+		"secret.txt":  "secret",
+	})
+	jailDir := filepath.Join(root, "jail")
+	if err := os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(jailDir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(jailDir, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "new.txt"), filepath.Join(jailDir, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("in.txt", filepath.Join(jailDir, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	jail, err := Sub(DirFS(root), "jail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, jail, "inside"); got != "in" {
+		t.Errorf("symlink inside the jail: got %q", got)
+	}
+	for _, name := range []string{"escape", "up/secret.txt"} {
+		if _, err := jail.Open(name); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("%s: got %v, want ErrPathEscapes", name, err)
+		}
+	}
+	if err := WriteFileFS(jail, "dangling", []byte("x"), 0o644); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("dangling: got %v", err)
+	}
+	if ok, _ := IsPathExist(filepath.Join(root, "new.txt")); ok {
+		t.Error("file created outside the jail")
+	}
+	// 链接本身可以删除
+	if err := jail.Remove("escape"); err != nil {
+		t.Errorf("removing the link: %v", err)
+	}
+	assertFileContent(t, filepath.Join(root, "secret.txt"), "secret")
+}
+
+func TestSubMemFS(t *testing.T) {
+	m := NewMemFS()
+	fillFS(t, m, map[string]string{"app/conf/a.yaml": "a"})
+	sub, err := Sub(m, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, sub, "conf/a.yaml"); got != "a" {
+		t.Errorf("got %q", got)
+	}
+	if err := WriteFileFS(sub, "b.txt", []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, m, "app/b.txt"); got != "b" {
+		t.Errorf("got %q", got)
+	}
+	var pe *fs.PathError
+	if _, err := sub.Stat("missing"); !errors.As(err, &pe) || pe.Path != "missing" {
+		t.Errorf("error path should be relative to the sub, got %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ro := ReadOnly(fstest.MapFS{"a.txt": {Data: []byte("a")}})
+	if got := readFS(t, ro, "a.txt"); got != "a" {
+		t.Errorf("got %q", got)
+	}
+	if err := WriteFileFS(ro, "a.txt", nil, 0o644); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("write: got %v", err)
+	}
+	if err := ro.Remove("a.txt"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("remove: got %v", err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	lower := fstest.MapFS{
+		"conf/a.yaml":    {Data: []byte("lower a"), Mode: 0o644},
+		"conf/b.yaml":    {Data: []byte("lower b"), Mode: 0o644},
+		"data/x/1.txt":   {Data: []byte("1"), Mode: 0o644},
+		"data/x/2.txt":   {Data: []byte("2"), Mode: 0o644},
+		"static/app.css": {Data: []byte("css"), Mode: 0o644},
+	}
+	upper := NewMemFS()
+	o := Overlay(lower, upper)
+
+	// 修改下层文件会先复制到上层
+	f, err := o.OpenFile("conf/a.yaml", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(f, " + upper")
+	f.Close()
+	if got := readFS(t, o, "conf/a.yaml"); got != "lower a + upper" {
+		t.Errorf("copy-up: got %q", got)
+	}
+	if got := string(lower["conf/a.yaml"].Data); got != "lower a" {
+		t.Errorf("lower was modified: %q", got)
+	}
+
+	if err := WriteFileFS(o, "conf/c.yaml", []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Remove("conf/b.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := IsPathExistFS(o, "conf/b.yaml"); ok {
+		t.Error("removed lower file is still visible")
+	}
+	entries, err := FindFS(o, "conf", WalkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rels(entries); !reflect.DeepEqual(got, []string{"a.yaml", "c.yaml"}) {
+		t.Errorf("merged listing: got %v", got)
+	}
+
+	// 删除后重建的目录不再显示下层内容
+	if err := o.RemoveAll("data/x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.MkdirAll("data/x", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := o.ReadDir("data/x"); len(entries) != 0 {
+		t.Errorf("recreated directory shows %d lower entries", len(entries))
+	}
+
+	if err := o.Rename("static", "public"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFS(t, o, "public/app.css"); got != "css" {
+		t.Errorf("renamed lower dir: got %q", got)
+	}
+	if ok, _ := IsPathExistFS(o, "static"); ok {
+		t.Error("old name still visible after rename")
+	}
+
+	if err := fstest.TestFS(o, "conf/a.yaml", "conf/c.yaml", "public/app.css", "data/x"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,69 @@
+package file
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether name matches the glob pattern.
+// Patterns use path.Match syntax on slash separated paths, plus "**" as a whole segment,
+// which matches zero or more directories. A pattern without a slash is matched against
+// the last element of name only, so "*.go" matches "a/b/c.go".
+//
+// 判断路径是否匹配glob模式，支持**匹配任意层级目录
+// 示例:
+//
+//	MatchGlob("**/*.go", "cmd/app/main.go") // 返回: true, nil
+//	MatchGlob("*.go", "cmd/main.go")        // 返回: true, nil
+//	MatchGlob("cmd/*.go", "cmd/app/main.go") // 返回: false, nil
+func MatchGlob(pattern, name string) (bool, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	name = strings.TrimPrefix(name, "./")
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(name))
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// MatchAnyGlob reports whether name matches at least one of patterns. See MatchGlob.
+//
+// 判断路径是否匹配任意一个glob模式
+func MatchAnyGlob(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		ok, err := MatchGlob(p, name)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// 连续的**等价于一个
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern, name[i:])
+				if err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
@@ -0,0 +1,39 @@
+package file
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{"*.go", "main.go", true, false},
+		{"*.go", "cmd/app/main.go", true, false},
+		{"*.go", "main.txt", false, false},
+		{"cmd/*.go", "cmd/main.go", true, false},
+		{"cmd/*.go", "cmd/app/main.go", false, false},
+		{"**/*.go", "main.go", true, false},
+		{"**/*.go", "cmd/app/main.go", true, false},
+		{"cmd/**", "cmd/app/main.go", true, false},
+		{"cmd/**/main.go", "cmd/main.go", true, false},
+		{"cmd/**/main.go", "cmd/a/b/main.go", true, false},
+		{"cmd/**/main.go", "pkg/a/main.go", false, false},
+		{"a/**/**/b", "a/x/b", true, false},
+		{"./src/*.ts", "src/index.ts", true, false},
+		{"[", "a", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := MatchGlob(tt.pattern, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MatchGlob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("MatchGlob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
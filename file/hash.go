@@ -0,0 +1,327 @@
+package file
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mudssky/goutils"
+)
+
+// HashAlgorithm selects the hash function used by Hash and friends.
+//
+// 哈希算法
+type HashAlgorithm int
+
+// Supported hash algorithms, SHA256 is the zero value.
+const (
+	SHA256 HashAlgorithm = iota
+	SHA1
+	SHA512
+	MD5
+	CRC32
+)
+
+// New returns a new hash.Hash computing the algorithm.
+func (a HashAlgorithm) New() hash.Hash {
+	switch a {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA512:
+		return sha512.New()
+	case CRC32:
+		return crc32.NewIEEE()
+	default:
+		return sha256.New()
+	}
+}
+
+func (a HashAlgorithm) String() string {
+	switch a {
+	case SHA256:
+		return "sha256"
+	case SHA1:
+		return "sha1"
+	case SHA512:
+		return "sha512"
+	case MD5:
+		return "md5"
+	case CRC32:
+		return "crc32"
+	default:
+		return fmt.Sprintf("HashAlgorithm(%d)", int(a))
+	}
+}
+
+// HashReader returns the hex encoded hash of everything read from r.
+//
+// 计算io.Reader内容的哈希
+func HashReader(r io.Reader, algo HashAlgorithm) (string, error) {
+	h := algo.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Hash returns the hex encoded hash of the file at path. The file is streamed, not read into memory.
+//
+// 计算文件的哈希，流式读取
+// 示例:
+//
+//	sum, err := file.Hash("release.tar.gz", file.SHA256)
+func Hash(path string, algo HashAlgorithm) (string, error) {
+	return HashFS(hostFS, path, algo)
+}
+
+// HashFS is Hash for the file name of fsys.
+//
+// 计算文件系统中文件的哈希
+func HashFS(fsys fs.FS, name string, algo HashAlgorithm) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return HashReader(f, algo)
+}
+
+// HashDir returns a hash of the whole tree under root that only depends on the relative paths,
+// the file contents and the symlink targets, so that two identical trees hash the same wherever they are.
+// Empty directories are part of the hash; permissions and times are not.
+//
+// 计算整个目录树的哈希，结果只与相对路径和文件内容有关
+func HashDir(root string, algo HashAlgorithm) (string, error) {
+	return HashDirFS(hostFS, root, algo)
+}
+
+// HashDirFS is HashDir for the directory root of fsys.
+//
+// 计算文件系统中目录树的哈希
+func HashDirFS(fsys fs.FS, root string, algo HashAlgorithm) (string, error) {
+	h := algo.New()
+	err := WalkFS(fsys, root, WalkOptions{}, func(e Entry) error {
+		switch {
+		case e.IsDir():
+			fmt.Fprintf(h, "d %s\x00", e.Rel)
+		case e.Symlink:
+			target, err := readlink(fsys, e.Path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "l %s\x00%s\x00", e.Rel, filepath.ToSlash(target))
+		default:
+			sum, err := HashFS(fsys, e.Path, algo)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "f %s\x00%s\x00", e.Rel, sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ManifestEntry is one line of a checksum manifest.
+//
+// 校验清单中的一行
+type ManifestEntry struct {
+	Sum string
+	// Path is slash separated and relative to the manifest root.
+	Path string
+}
+
+// ManifestFailure describes a manifest entry that did not verify.
+//
+// 校验失败的项
+type ManifestFailure struct {
+	ManifestEntry
+	// Got is the actual hash, empty if the file could not be read.
+	Got string
+	// Err is set if the file could not be read, e.g. because it is missing.
+	Err error
+}
+
+// Manifest hashes every regular file under root, in lexical order.
+//
+// 生成目录下所有文件的校验清单
+func Manifest(root string, algo HashAlgorithm) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	err := Walk(root, WalkOptions{Filters: []Filter{regularOnly}}, func(e Entry) error {
+		sum, err := Hash(e.Path, algo)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, ManifestEntry{Sum: sum, Path: e.Rel})
+		return nil
+	})
+	return entries, err
+}
+
+// WriteManifest writes entries in the format of sha256sum and friends ("<sum>  <path>"),
+// so that `sha256sum -c` can check them. Like sha256sum, paths containing a backslash or a line
+// break are escaped and their line is prefixed with a backslash.
+//
+// 以sha256sum兼容的格式写出校验清单
+func WriteManifest(w io.Writer, entries []ManifestEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		prefix, path := "", e.Path
+		if strings.ContainsAny(path, "\\\n\r") {
+			prefix, path = "\\", manifestEscaper.Replace(path)
+		}
+		if _, err := fmt.Fprintf(bw, "%s%s  %s\n", prefix, e.Sum, path); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadManifest parses a manifest in the format of sha256sum and friends, accepting both the
+// text ("<sum>  <path>") and binary ("<sum> *<path>") markers. Empty lines and # comments are skipped.
+// Lines starting with a backslash have escaped paths, see WriteManifest.
+//
+// 读取sha256sum格式的校验清单
+func ReadManifest(r io.Reader) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		// sha256sum对含反斜杠或换行的路径转义，并在行首加反斜杠
+		escaped := strings.HasPrefix(text, "\\")
+		if escaped {
+			text = text[1:]
+		}
+		sum, path, ok := strings.Cut(text, " ")
+		if !ok || sum == "" || len(path) < 2 || (path[0] != ' ' && path[0] != '*') {
+			return nil, fmt.Errorf("manifest line %d: invalid format", line)
+		}
+		path = path[1:]
+		if escaped {
+			if path, ok = unescapeManifestPath(path); !ok {
+				return nil, fmt.Errorf("manifest line %d: invalid escape", line)
+			}
+		}
+		entries = append(entries, ManifestEntry{Sum: strings.ToLower(sum), Path: path})
+	}
+	return entries, scanner.Err()
+}
+
+// manifestEscaper escapes paths the way sha256sum does.
+var manifestEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+
+// unescapeManifestPath reverses manifestEscaper, reporting false for unknown escapes.
+func unescapeManifestPath(path string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '\\' {
+			b.WriteByte(path[i])
+			continue
+		}
+		i++
+		if i == len(path) {
+			return "", false
+		}
+		switch path[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// VerifyManifest checks entries against the files under root and returns the ones that failed.
+//
+// 校验目录下的文件，返回校验失败的项
+// 示例:
+//
+//	f, _ := os.Open("SHA256SUMS")
+//	entries, _ := file.ReadManifest(f)
+//	failures := file.VerifyManifest("dist", entries, file.SHA256)
+func VerifyManifest(root string, entries []ManifestEntry, algo HashAlgorithm) []ManifestFailure {
+	var failures []ManifestFailure
+	for _, e := range entries {
+		got, err := Hash(filepath.Join(root, filepath.FromSlash(e.Path)), algo)
+		if err != nil {
+			failures = append(failures, ManifestFailure{ManifestEntry: e, Err: err})
+			continue
+		}
+		if !strings.EqualFold(got, e.Sum) {
+			failures = append(failures, ManifestFailure{ManifestEntry: e, Got: got})
+		}
+	}
+	return failures
+}
+
+// FindDuplicates returns the groups of regular files under root that have identical content.
+// Files are first grouped by size, and only files sharing a size are hashed.
+// Each group is sorted by path and groups are sorted by their first path.
+//
+// 查找内容相同的文件，先按大小分组，再对大小相同的文件计算哈希
+// 示例:
+//
+//	groups, err := file.FindDuplicates("photos") // [["photos/a.jpg", "photos/copy/a.jpg"]]
+func FindDuplicates(root string) ([][]string, error) {
+	files, err := Find(root, WalkOptions{Filters: []Filter{regularOnly}})
+	if err != nil {
+		return nil, err
+	}
+
+	var result [][]string
+	bySize := goutils.GroupBy(files, func(e Entry) int64 { return e.Size() })
+	for _, group := range bySize {
+		if len(group) < 2 {
+			continue
+		}
+		sums := make(map[string]string, len(group))
+		for _, e := range group {
+			sum, err := Hash(e.Path, SHA256)
+			if err != nil {
+				return nil, err
+			}
+			sums[e.Path] = sum
+		}
+		byHash := goutils.GroupBy(group, func(e Entry) string { return sums[e.Path] })
+		for _, same := range byHash {
+			if len(same) < 2 {
+				continue
+			}
+			paths := goutils.Map(same, func(e Entry, _ int) string { return e.Path })
+			sort.Strings(paths)
+			result = append(result, paths)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+	return result, nil
+}
+
+func regularOnly(e Entry) bool {
+	return e.Mode().IsRegular()
+}
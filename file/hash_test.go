@@ -0,0 +1,159 @@
+package file
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	root := makeTree(t, map[string]string{"hello.txt": "hello"})
+	path := filepath.Join(root, "hello.txt")
+
+	tests := []struct {
+		algo HashAlgorithm
+		want string
+	}{
+		{SHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{SHA1, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{MD5, "5d41402abc4b2a76b9719d911017c592"},
+		{CRC32, "3610a686"},
+		{SHA512, "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algo.String(), func(t *testing.T) {
+			got, err := Hash(path, tt.algo)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Hash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Hash(filepath.Join(root, "missing"), SHA256); err == nil {
+		t.Error("Hash() of missing file should fail")
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	files := map[string]string{"a.txt": "a", "sub/b.txt": "b", "empty/": ""}
+	root1 := makeTree(t, files)
+	root2 := makeTree(t, files)
+
+	h1, err := HashDir(root1, SHA256)
+	if err != nil {
+		t.Fatalf("HashDir() error = %v", err)
+	}
+	h2, _ := HashDir(root2, SHA256)
+	if h1 != h2 {
+		t.Errorf("HashDir() of identical trees differ: %v != %v", h1, h2)
+	}
+
+	if err := os.WriteFile(filepath.Join(root2, "sub", "b.txt"), []byte("B"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if h3, _ := HashDir(root2, SHA256); h3 == h1 {
+		t.Error("HashDir() should change with content")
+	}
+}
+
+func TestManifest(t *testing.T) {
+	root := makeTree(t, map[string]string{"hello.txt": "hello", "sub/a.txt": "a"})
+
+	entries, err := Manifest(root, SHA256)
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, entries); err != nil {
+		t.Fatalf("WriteManifest() error = %v", err)
+	}
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt\n" +
+		"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  sub/a.txt\n"
+	if buf.String() != want {
+		t.Errorf("WriteManifest() = %q, want %q", buf.String(), want)
+	}
+
+	read, err := ReadManifest(strings.NewReader("# comment\r\n" + buf.String() + "\nCA978112CA1BBDCAFAC231B39A23DC4DA786EFF8147C4E72B9807785AFEE48BB *missing.txt\n"))
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(read) != 3 || !reflect.DeepEqual(read[:2], entries) || read[2].Path != "missing.txt" {
+		t.Errorf("ReadManifest() = %v", read)
+	}
+	if _, err := ReadManifest(strings.NewReader("garbage\n")); err == nil {
+		t.Error("ReadManifest() should reject invalid lines")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "hello.txt"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	failures := VerifyManifest(root, read, SHA256)
+	if len(failures) != 2 {
+		t.Fatalf("VerifyManifest() = %v, want 2 failures", failures)
+	}
+	if failures[0].Path != "hello.txt" || failures[0].Got == "" || failures[0].Err != nil {
+		t.Errorf("VerifyManifest() failure = %+v", failures[0])
+	}
+	if failures[1].Path != "missing.txt" || !os.IsNotExist(failures[1].Err) {
+		t.Errorf("VerifyManifest() failure = %+v", failures[1])
+	}
+}
+
+func TestManifestEscape(t *testing.T) {
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	entries := []ManifestEntry{
+		{Sum: sum, Path: `dir\a.txt`},
+		{Sum: sum, Path: "line\nbreak.txt"},
+		{Sum: sum, Path: "plain.txt"},
+	}
+	var buf bytes.Buffer
+	if err := WriteManifest(&buf, entries); err != nil {
+		t.Fatalf("WriteManifest() error = %v", err)
+	}
+	want := `\` + sum + `  dir\\a.txt` + "\n" +
+		`\` + sum + `  line\nbreak.txt` + "\n" +
+		sum + "  plain.txt\n"
+	if buf.String() != want {
+		t.Errorf("WriteManifest() = %q, want %q", buf.String(), want)
+	}
+
+	read, err := ReadManifest(&buf)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if !reflect.DeepEqual(read, entries) {
+		t.Errorf("ReadManifest() = %q, want %q", read, entries)
+	}
+	if _, err := ReadManifest(strings.NewReader(`\` + sum + `  bad\x` + "\n")); err == nil {
+		t.Error("ReadManifest() should reject unknown escapes")
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.txt":        "same",
+		"b/a-copy.txt": "same",
+		"c.txt":        "diff",
+		"d.txt":        "other size",
+		"e.txt":        "other size",
+		"f.txt":        "unique",
+	})
+
+	got, err := FindDuplicates(root)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	want := [][]string{
+		{filepath.Join(root, "a.txt"), filepath.Join(root, "b", "a-copy.txt")},
+		{filepath.Join(root, "d.txt"), filepath.Join(root, "e.txt")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDuplicates() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,326 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// ErrLineTooLong is returned when a line exceeds LineOptions.MaxLineSize.
+var ErrLineTooLong = errors.New("line too long")
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// LineOptions configures a LineReader.
+//
+// 按行读取的配置项
+type LineOptions struct {
+	// MaxLineSize limits the length of a line in bytes, longer lines fail with ErrLineTooLong.
+	// 0 means no limit, unlike bufio.Scanner which stops at 64KB.
+	MaxLineSize int
+	// KeepBOM keeps a leading UTF-8 byte order mark, which is stripped by default.
+	KeepBOM bool
+	// KeepCR keeps the \r of \r\n line endings, which is stripped by default.
+	KeepCR bool
+}
+
+// LineReader iterates lazily over the lines of a reader. Lines are returned without their line ending.
+//
+// 惰性按行读取
+// 示例:
+//
+//	lines, err := file.OpenLines("access.log", file.LineOptions{})
+//	if err != nil { ... }
+//	defer lines.Close()
+//	for lines.Next() {
+//		fmt.Println(lines.LineNumber(), lines.Text())
+//	}
+//	if err := lines.Err(); err != nil { ... }
+type LineReader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	opts   LineOptions
+	line   []byte
+	n      int
+	err    error
+}
+
+// NewLineReader returns a LineReader over r.
+//
+// 创建按行读取器
+func NewLineReader(r io.Reader, opts LineOptions) *LineReader {
+	return &LineReader{r: bufio.NewReader(r), opts: opts}
+}
+
+// OpenLines opens the file at path for reading line by line. The caller must Close it.
+//
+// 打开文件按行读取，使用完需要Close
+func OpenLines(path string, opts LineOptions) (*LineReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	lr := NewLineReader(f, opts)
+	lr.closer = f
+	return lr, nil
+}
+
+// Next advances to the next line. It returns false at the end of the input or on error.
+//
+// 读取下一行，结束或出错时返回false
+func (lr *LineReader) Next() bool {
+	if lr.err != nil {
+		return false
+	}
+	lr.line = lr.line[:0]
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.line = append(lr.line, chunk...)
+		if lr.opts.MaxLineSize > 0 && len(bytes.TrimRight(lr.line, "\r\n")) > lr.opts.MaxLineSize {
+			lr.err = fmt.Errorf("line %d: %w", lr.n+1, ErrLineTooLong)
+			return false
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			lr.err = err
+			if len(lr.line) == 0 {
+				return false
+			}
+		}
+		break
+	}
+
+	lr.n++
+	lr.line = bytes.TrimSuffix(lr.line, []byte("\n"))
+	if !lr.opts.KeepCR {
+		lr.line = bytes.TrimSuffix(lr.line, []byte("\r"))
+	}
+	if lr.n == 1 && !lr.opts.KeepBOM {
+		lr.line = bytes.TrimPrefix(lr.line, utf8BOM)
+	}
+	return true
+}
+
+// Bytes returns the current line. The slice is only valid until the next call to Next.
+func (lr *LineReader) Bytes() []byte {
+	return lr.line
+}
+
+// Text returns the current line as a string.
+func (lr *LineReader) Text() string {
+	return string(lr.line)
+}
+
+// LineNumber returns the 1-based number of the current line.
+func (lr *LineReader) LineNumber() int {
+	return lr.n
+}
+
+// Err returns the error that stopped the iteration, if any. Reaching the end of the input is not an error.
+func (lr *LineReader) Err() error {
+	if lr.err == io.EOF {
+		return nil
+	}
+	return lr.err
+}
+
+// Close closes the file opened by OpenLines. It does nothing for readers created by NewLineReader.
+func (lr *LineReader) Close() error {
+	if lr.closer == nil {
+		return nil
+	}
+	return lr.closer.Close()
+}
+
+// ReadLines returns all lines of the file at path, without line endings or a leading BOM.
+// A final newline does not produce an empty last line.
+//
+// 读取文件的所有行，兼容\r\n换行，去掉UTF-8 BOM
+func ReadLines(path string) ([]string, error) {
+	var lines []string
+	err := EachLine(path, func(line string, _ int) error {
+		lines = append(lines, line)
+		return nil
+	})
+	return lines, err
+}
+
+// EachLine calls fn with every line of the file at path and its 1-based number, streaming the file.
+// Lines of any length are supported. Returning an error from fn stops the iteration and returns that error.
+//
+// 流式按行处理文件，不受bufio.Scanner 64KB行长度的限制
+func EachLine(path string, fn func(line string, n int) error) error {
+	lr, err := OpenLines(path, LineOptions{})
+	if err != nil {
+		return err
+	}
+	defer lr.Close()
+	for lr.Next() {
+		if err := fn(lr.Text(), lr.LineNumber()); err != nil {
+			return err
+		}
+	}
+	return lr.Err()
+}
+
+// WriteLines writes lines to the file at path, each followed by \n, replacing its content.
+//
+// 按行写入文件，覆盖原有内容
+func WriteLines(path string, lines []string, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	return writeLines(f, lines)
+}
+
+// AppendLines appends lines to the file at path, each followed by \n, creating the file if needed.
+// If the file does not end with a newline, one is added first so that the lines are not glued to the last one.
+//
+// 按行追加到文件末尾
+func AppendLines(path string, lines []string, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if size := info.Size(); size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if last[0] != '\n' {
+			lines = append([]string{""}, lines...)
+		}
+	}
+	return writeLines(f, lines)
+}
+
+func writeLines(f *os.File, lines []string) error {
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		if _, err := w.WriteString(line); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NormalizeEOL converts every \r\n, \r and \n line ending in data to eol, e.g. "\n" or "\r\n".
+//
+// 统一换行符
+// 示例:
+//
+//	unix := file.NormalizeEOL(data, "\n")
+func NormalizeEOL(data []byte, eol string) []byte {
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				i++
+			}
+			result = append(result, eol...)
+		case '\n':
+			result = append(result, eol...)
+		default:
+			result = append(result, data[i])
+		}
+	}
+	return result
+}
+
+// StripBOM removes a leading UTF-8 byte order mark from data.
+//
+// 去掉开头的UTF-8 BOM
+func StripBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
+
+// SkipBOM returns a reader that skips a leading UTF-8 byte order mark of r.
+//
+// 返回跳过UTF-8 BOM的Reader
+func SkipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if head, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(head, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+	return br
+}
+
+// Tail returns the last n lines of the file at path. It reads the file backwards from the end,
+// so only the needed part of a large file is read.
+//
+// 读取文件的最后n行，从文件末尾向前读取，适合大文件
+// 示例:
+//
+//	lines, err := file.Tail("app.log", 100)
+func Tail(path string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 8 * 1024
+	var (
+		offset   = info.Size()
+		chunks   [][]byte
+		newlines int
+	)
+	for offset > 0 && newlines < n {
+		read := min(int64(chunkSize), offset)
+		offset -= read
+		chunk := make([]byte, read)
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		counted := chunk
+		if len(chunks) == 0 {
+			// 末尾的换行不算一行，需要n个其他换行才能确定n行的起点
+			counted = bytes.TrimSuffix(chunk, []byte("\n"))
+		}
+		newlines += bytes.Count(counted, []byte("\n"))
+		chunks = append(chunks, chunk)
+	}
+	// 块是从后往前读取的，反转后一次拼接
+	slices.Reverse(chunks)
+	buf := bytes.Join(chunks, nil)
+
+	lr := NewLineReader(bytes.NewReader(buf), LineOptions{KeepBOM: offset > 0})
+	var lines []string
+	for lr.Next() {
+		lines = append(lines, lr.Text())
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, lr.Err()
+}
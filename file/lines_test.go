@@ -0,0 +1,176 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	writeFile(t, path, "\xEF\xBB\xBFfirst\r\nsecond\n\nlast")
+	got, err := ReadLines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first", "second", "", "last"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	writeFile(t, path, "a\nb\n")
+	if got, _ := ReadLines(path); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("trailing newline: got %q", got)
+	}
+	writeFile(t, path, "")
+	if got, _ := ReadLines(path); len(got) != 0 {
+		t.Errorf("empty file: got %q", got)
+	}
+}
+
+func TestEachLineLong(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "long.txt")
+	long := strings.Repeat("x", 200*1024)
+	writeFile(t, path, "short\n"+long+"\nend\n")
+
+	var lengths []int
+	err := EachLine(path, func(line string, n int) error {
+		if n != len(lengths)+1 {
+			t.Errorf("line number %d, want %d", n, len(lengths)+1)
+		}
+		lengths = append(lengths, len(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{5, len(long), 3}; !reflect.DeepEqual(lengths, want) {
+		t.Errorf("got %v, want %v", lengths, want)
+	}
+
+	stop := errors.New("stop")
+	if err := EachLine(path, func(string, int) error { return stop }); err != stop {
+		t.Errorf("got %v, want the callback error", err)
+	}
+}
+
+func TestLineReaderMaxLineSize(t *testing.T) {
+	lr := NewLineReader(strings.NewReader("ok\ntoo long\n"), LineOptions{MaxLineSize: 4})
+	if !lr.Next() || lr.Text() != "ok" {
+		t.Fatalf("first line: %q", lr.Text())
+	}
+	if lr.Next() {
+		t.Fatalf("second line should fail, got %q", lr.Text())
+	}
+	if err := lr.Err(); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("got %v, want ErrLineTooLong", err)
+	}
+}
+
+func TestLineReaderKeep(t *testing.T) {
+	lr := NewLineReader(strings.NewReader("\xEF\xBB\xBFa\r\nb"), LineOptions{KeepBOM: true, KeepCR: true})
+	var got []string
+	for lr.Next() {
+		got = append(got, lr.Text())
+	}
+	if want := []string{"\xEF\xBB\xBFa\r", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteAppendLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := WriteLines(path, []string{"a", "b"}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "a\nb\n")
+	if err := AppendLines(path, []string{"c"}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "a\nb\nc\n")
+
+	writeFile(t, path, "no newline")
+	if err := AppendLines(path, []string{"next"}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "no newline\nnext\n")
+
+	created := filepath.Join(t.TempDir(), "new.txt")
+	if err := AppendLines(created, []string{"x"}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, created, "x\n")
+}
+
+func TestNormalizeEOL(t *testing.T) {
+	in := []byte("a\r\nb\rc\nd")
+	if got := string(NormalizeEOL(in, "\n")); got != "a\nb\nc\nd" {
+		t.Errorf("LF: got %q", got)
+	}
+	if got := string(NormalizeEOL(in, "\r\n")); got != "a\r\nb\r\nc\r\nd" {
+		t.Errorf("CRLF: got %q", got)
+	}
+}
+
+func TestBOM(t *testing.T) {
+	if got := string(StripBOM([]byte("\xEF\xBB\xBFhi"))); got != "hi" {
+		t.Errorf("StripBOM: got %q", got)
+	}
+	data, err := io.ReadAll(SkipBOM(strings.NewReader("\xEF\xBB\xBFhi")))
+	if err != nil || string(data) != "hi" {
+		t.Errorf("SkipBOM: got %q, %v", data, err)
+	}
+	data, _ = io.ReadAll(SkipBOM(strings.NewReader("h")))
+	if string(data) != "h" {
+		t.Errorf("SkipBOM short input: got %q", data)
+	}
+}
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.log")
+	var lines []string
+	for i := 1; i <= 5000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := WriteLines(path, lines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, 1, 3, 2000, 5000, 6000} {
+		got, err := Tail(path, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := lines[max(len(lines)-n, 0):]
+		if n == 0 {
+			want = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Tail(%d): got %d lines, want %d", n, len(got), len(want))
+		}
+	}
+
+	// 跨越多个块的长行
+	long := filepath.Join(dir, "long.log")
+	longLines := []string{strings.Repeat("a", 20000), strings.Repeat("b", 20000), strings.Repeat("c", 9000)}
+	if err := WriteLines(long, longLines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Tail(long, 2); !reflect.DeepEqual(got, longLines[1:]) {
+		t.Errorf("long lines: got %d lines", len(got))
+	}
+
+	small := filepath.Join(dir, "small.txt")
+	writeFile(t, small, "\xEF\xBB\xBFa\r\nb")
+	if got, _ := Tail(small, 5); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("small: got %q", got)
+	}
+	if _, err := Tail(filepath.Join(dir, "missing"), 1); !os.IsNotExist(err) {
+		t.Errorf("missing: got %v", err)
+	}
+}
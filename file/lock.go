@@ -0,0 +1,251 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLocked is returned by TryLock when the lock is held by someone else.
+	ErrLocked = errors.New("file is locked")
+	// ErrAlreadyRunning is returned by NewPIDFile when the pid file belongs to a running process.
+	ErrAlreadyRunning = errors.New("process already running")
+
+	errLockHeld = errors.New("lock held")
+)
+
+// LockMode selects between exclusive and shared locks.
+//
+// 锁模式
+type LockMode int
+
+const (
+	// Exclusive locks can only be held by one owner at a time.
+	Exclusive LockMode = iota
+	// Shared locks can be held by many owners at once, but not together with an exclusive lock.
+	Shared
+)
+
+// FileLock is an advisory lock on a file, held until Unlock is called or the process exits.
+// Locks are bound to the open file, so two FileLocks on one path conflict even within a process.
+//
+// 文件锁，Linux和macOS上使用flock，Windows上使用LockFileEx
+type FileLock struct {
+	mu   sync.Mutex
+	f    *os.File
+	path string
+}
+
+// Lock acquires a lock on the file at path, creating it if needed, and waits until it is available.
+// The lock file is not removed on Unlock, as removing it would race with other processes locking it.
+//
+// 加锁，锁被占用时等待
+// 示例:
+//
+//	lock, err := file.Lock("/var/run/backup.lock", file.Exclusive)
+//	if err != nil { ... }
+//	defer lock.Unlock()
+func Lock(path string, mode LockMode) (*FileLock, error) {
+	return acquire(path, mode, true)
+}
+
+// TryLock acquires a lock on the file at path without waiting. It returns ErrLocked if the lock is held.
+//
+// 尝试加锁，锁被占用时立即返回ErrLocked
+func TryLock(path string, mode LockMode) (*FileLock, error) {
+	return acquire(path, mode, false)
+}
+
+// LockContext waits for a lock on the file at path until ctx is done. Since flock cannot be interrupted,
+// it polls with TryLock, backing off up to 100ms between attempts.
+//
+// 加锁，等待时可以被ctx取消
+func LockContext(ctx context.Context, path string, mode LockMode) (*FileLock, error) {
+	delay := time.Millisecond
+	for {
+		l, err := TryLock(path, mode)
+		if !errors.Is(err, ErrLocked) {
+			return l, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(delay*2, 100*time.Millisecond)
+	}
+}
+
+func acquire(path string, mode LockMode, block bool) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, mode == Shared, block); err != nil {
+		_ = f.Close()
+		if errors.Is(err, errLockHeld) {
+			err = ErrLocked
+		}
+		return nil, &os.PathError{Op: "lock", Path: path, Err: err}
+	}
+	return &FileLock{f: f, path: path}, nil
+}
+
+// Path returns the path of the lock file.
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock. Calling it again does nothing.
+func (l *FileLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// PIDFile is a file holding the pid of the running process, used to prevent a program from running twice.
+//
+// PID文件，防止程序重复运行
+type PIDFile struct {
+	path string
+	pid  int
+}
+
+// pidFiles holds the absolute paths of the pid files created by this process, to tell them apart from
+// stale files of an earlier process that had the same pid, e.g. pid 1 in a restarted container.
+var pidFiles sync.Map
+
+// NewPIDFile creates the pid file at path with the pid of the current process. If the file exists and
+// names a running process, it fails with ErrAlreadyRunning; if the process is gone, the stale file is replaced.
+// Note that a stale file whose pid was reused by an unrelated process is taken as running.
+//
+// The check and replacement happen under an exclusive lock on path+".lock", which is left in place like
+// the files of Lock, and the pid is written to a temporary file that is hard linked to path, so that
+// concurrent callers never see a missing or empty pid file.
+//
+// 创建PID文件，检测并替换已退出进程留下的PID文件
+// 示例:
+//
+//	pf, err := file.NewPIDFile("/var/run/worker.pid")
+//	if errors.Is(err, file.ErrAlreadyRunning) {
+//		return // 已有实例在运行
+//	}
+//	defer pf.Close()
+func NewPIDFile(path string) (*PIDFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := Lock(path+".lock", Exclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	pid := os.Getpid()
+	old, err := ReadPID(path)
+	switch {
+	case err == nil && old == pid:
+		if _, ours := pidFiles.Load(abs); ours {
+			return nil, fmt.Errorf("%s: %w with pid %d", path, ErrAlreadyRunning, old)
+		}
+	case err == nil && processAlive(old):
+		return nil, fmt.Errorf("%s: %w with pid %d", path, ErrAlreadyRunning, old)
+	}
+	if err == nil || !os.IsNotExist(err) {
+		// 进程已退出或文件内容无效，删除后重新创建
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = fmt.Fprintf(tmp, "%d\n", pid)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 硬链接在目标已存在时失败，不会覆盖不使用锁的其他程序创建的文件
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, ErrAlreadyRunning)
+		}
+		return nil, err
+	}
+	pidFiles.Store(abs, true)
+	return &PIDFile{path: path, pid: pid}, nil
+}
+
+// ReadPID reads the pid stored in the pid file at path.
+//
+// 读取PID文件中的进程号
+func ReadPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: invalid pid %q", path, strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// Path returns the path of the pid file.
+func (p *PIDFile) Path() string {
+	return p.path
+}
+
+// PID returns the pid written to the file.
+func (p *PIDFile) PID() int {
+	return p.pid
+}
+
+// Close removes the pid file, unless it was replaced by another process in the meantime.
+//
+// 删除PID文件
+func (p *PIDFile) Close() error {
+	lock, err := Lock(p.path+".lock", Exclusive)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if abs, err := filepath.Abs(p.path); err == nil {
+		pidFiles.Delete(abs)
+	}
+
+	pid, err := ReadPID(p.path)
+	if os.IsNotExist(err) || (err == nil && pid != p.pid) {
+		return nil
+	}
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
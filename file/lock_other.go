@@ -0,0 +1,17 @@
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not implemented on this platform.
+func lockFile(f *os.File, shared, block bool) error {
+	return errors.ErrUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return errors.ErrUnsupported
+}
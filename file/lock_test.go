@@ -0,0 +1,203 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.lock")
+	l, err := TryLock(path, Exclusive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TryLock(path, Exclusive); !errors.Is(err, ErrLocked) {
+		t.Errorf("second exclusive: got %v, want ErrLocked", err)
+	}
+	if _, err := TryLock(path, Shared); !errors.Is(err, ErrLocked) {
+		t.Errorf("shared while exclusive: got %v, want ErrLocked", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Errorf("second Unlock: %v", err)
+	}
+
+	s1, err := TryLock(path, Shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := TryLock(path, Shared)
+	if err != nil {
+		t.Fatalf("two shared locks should coexist: %v", err)
+	}
+	if _, err := TryLock(path, Exclusive); !errors.Is(err, ErrLocked) {
+		t.Errorf("exclusive while shared: got %v, want ErrLocked", err)
+	}
+	s1.Unlock()
+	s2.Unlock()
+}
+
+func TestLockWaits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.lock")
+	l, err := Lock(path, Exclusive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan *FileLock)
+	go func() {
+		l2, err := Lock(path, Exclusive)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- l2
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Lock should wait for the holder")
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.Unlock()
+	select {
+	case l2 := <-acquired:
+		l2.Unlock()
+	case <-time.After(2 * time.Second):
+		t.Fatal("Lock was not acquired after Unlock")
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.lock")
+	l, err := Lock(path, Exclusive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := LockContext(ctx, path, Shared); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want DeadlineExceeded", err)
+	}
+
+	time.AfterFunc(30*time.Millisecond, func() { l.Unlock() })
+	l2, err := LockContext(context.Background(), path, Exclusive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.Unlock()
+}
+
+// deadPID returns the pid of a process that has exited.
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.pid")
+	pf, err := NewPIDFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := ReadPID(path); err != nil || pid != os.Getpid() || pf.PID() != pid {
+		t.Errorf("ReadPID: got %d, %v", pid, err)
+	}
+	if err := pf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := IsPathExist(path); ok {
+		t.Error("Close should remove the pid file")
+	}
+
+	// 运行中的进程
+	writeFile(t, path, strconv.Itoa(os.Getppid())+"\n")
+	if _, err := NewPIDFile(path); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("running: got %v, want ErrAlreadyRunning", err)
+	}
+
+	// 已退出的进程
+	writeFile(t, path, strconv.Itoa(deadPID(t)))
+	pf, err = NewPIDFile(path)
+	if err != nil {
+		t.Fatalf("stale: %v", err)
+	}
+	defer pf.Close()
+	assertFileContent(t, path, strconv.Itoa(os.Getpid())+"\n")
+
+	// 被其他进程替换后不删除
+	writeFile(t, path, "12345")
+	if err := pf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, path, "12345")
+}
+
+func TestPIDFileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.pid")
+	writeFile(t, path, strconv.Itoa(deadPID(t)))
+
+	const n = 16
+	var wg sync.WaitGroup
+	results := make(chan error, n)
+	files := make(chan *PIDFile, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pf, err := NewPIDFile(path)
+			if err == nil {
+				files <- pf
+			}
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(files)
+
+	var ok int
+	for err := range results {
+		switch {
+		case err == nil:
+			ok++
+		case !errors.Is(err, ErrAlreadyRunning):
+			t.Errorf("NewPIDFile() error = %v", err)
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d callers own the pid file, want 1", ok)
+	}
+	assertFileContent(t, path, strconv.Itoa(os.Getpid())+"\n")
+	for pf := range files {
+		if err := pf.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("pid file still exists after Close: %v", err)
+	}
+}
+
+func TestReadPIDInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.pid")
+	writeFile(t, path, "garbage")
+	if _, err := ReadPID(path); err == nil {
+		t.Error("expected an error")
+	}
+	pf, err := NewPIDFile(path)
+	if err != nil {
+		t.Fatalf("invalid content should be replaced: %v", err)
+	}
+	pf.Close()
+}
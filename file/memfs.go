@@ -0,0 +1,464 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNotEmpty = errors.New("directory not empty")
+
+// MemFS is an in-memory FS, safe for concurrent use. It does not support symlinks.
+// It is meant for unit tests of code written against FS.
+//
+// 内存文件系统，用于单元测试
+// 示例:
+//
+//	fsys := file.NewMemFS()
+//	_ = file.WriteFileFS(fsys, "conf/app.json", []byte("{}"), 0o644)
+//	ok, _ := file.IsPathExistFS(fsys, "conf/app.json") // true
+type MemFS struct {
+	mu    sync.RWMutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	mode    fs.FileMode
+	modTime time.Time
+	data    []byte
+}
+
+// NewMemFS returns an empty MemFS with only the root directory.
+//
+// 创建内存文件系统
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{
+		".": {mode: fs.ModeDir | 0o755, modTime: time.Now()},
+	}}
+}
+
+func memError(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// lookup returns the node of name, the caller holds m.mu.
+func (m *MemFS) lookup(op, name string) (*memNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, memError(op, name, fs.ErrInvalid)
+	}
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, memError(op, name, fs.ErrNotExist)
+	}
+	return n, nil
+}
+
+// parentDir checks that the parent of name is a directory, the caller holds m.mu.
+func (m *MemFS) parentDir(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return memError(op, name, fs.ErrInvalid)
+	}
+	parent, ok := m.nodes[path.Dir(name)]
+	if !ok {
+		return memError(op, name, fs.ErrNotExist)
+	}
+	if !parent.mode.IsDir() {
+		return memError(op, name, errNotDir)
+	}
+	return nil
+}
+
+// children returns the names of the direct children of dir, sorted. The caller holds m.mu.
+func (m *MemFS) children(dir string) []string {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	var names []string
+	for name := range m.nodes {
+		if name != "." && strings.HasPrefix(name, prefix) && !strings.Contains(name[len(prefix):], "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(name), nil
+}
+
+// Lstat is Stat, as MemFS has no symlinks.
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(name)
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, memError("readdir", name, errNotDir)
+	}
+	return m.dirEntries(name), nil
+}
+
+func (m *MemFS) dirEntries(name string) []fs.DirEntry {
+	names := m.children(name)
+	entries := make([]fs.DirEntry, len(names))
+	for i, child := range names {
+		entries[i] = fs.FileInfoToDirEntry(m.nodes[child].info(child))
+	}
+	return entries
+}
+
+// ReadFile returns the content of the file name.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.mode.IsDir() {
+		return nil, memError("read", name, errIsDir)
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// Readlink always fails, as MemFS has no symlinks.
+func (m *MemFS) Readlink(name string) (string, error) {
+	if _, err := m.Stat(name); err != nil {
+		return "", err
+	}
+	return "", memError("readlink", name, fs.ErrInvalid)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup("open", name)
+	switch {
+	case err == nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, memError("open", name, fs.ErrExist)
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		if err := m.parentDir("open", name); err != nil {
+			return nil, err
+		}
+		n = &memNode{mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[name] = n
+	case err != nil:
+		return nil, err
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if n.mode.IsDir() {
+		if writable {
+			return nil, memError("open", name, errIsDir)
+		}
+		return &memFile{fsys: m, node: n, name: name, entries: m.dirEntries(name)}, nil
+	}
+	if writable && flag&os.O_TRUNC != 0 {
+		n.data, n.modTime = nil, time.Now()
+	}
+	return &memFile{
+		fsys:     m,
+		node:     n,
+		name:     name,
+		readable: flag&os.O_WRONLY == 0,
+		writable: writable,
+		append:   flag&os.O_APPEND != 0,
+	}, nil
+}
+
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.lookup("mkdir", name); err == nil {
+		return memError("mkdir", name, fs.ErrExist)
+	}
+	if err := m.parentDir("mkdir", name); err != nil {
+		return err
+	}
+	m.nodes[name] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) {
+		return memError("mkdir", name, fs.ErrInvalid)
+	}
+	var missing []string
+	for p := name; ; p = path.Dir(p) {
+		n, ok := m.nodes[p]
+		if ok {
+			if !n.mode.IsDir() {
+				return memError("mkdir", p, errNotDir)
+			}
+			break
+		}
+		missing = append(missing, p)
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		m.nodes[missing[i]] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup("remove", name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return memError("remove", name, fs.ErrInvalid)
+	}
+	if n.mode.IsDir() && len(m.children(name)) > 0 {
+		return memError("remove", name, errNotEmpty)
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) || name == "." {
+		return memError("removeall", name, fs.ErrInvalid)
+	}
+	for p := range m.nodes {
+		if p == name || strings.HasPrefix(p, name+"/") {
+			delete(m.nodes, p)
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup("rename", oldname)
+	if err != nil {
+		return err
+	}
+	if err := m.parentDir("rename", newname); err != nil {
+		return err
+	}
+	if oldname == newname {
+		return nil
+	}
+	if n.mode.IsDir() && strings.HasPrefix(newname, oldname+"/") {
+		return memError("rename", newname, fs.ErrInvalid)
+	}
+	if existing, ok := m.nodes[newname]; ok {
+		switch {
+		case existing.mode.IsDir() != n.mode.IsDir() && existing.mode.IsDir():
+			return memError("rename", newname, errIsDir)
+		case existing.mode.IsDir() != n.mode.IsDir():
+			return memError("rename", newname, errNotDir)
+		case existing.mode.IsDir() && len(m.children(newname)) > 0:
+			return memError("rename", newname, errNotEmpty)
+		}
+	}
+
+	moved := map[string]*memNode{newname: n}
+	for p, child := range m.nodes {
+		if strings.HasPrefix(p, oldname+"/") {
+			moved[newname+p[len(oldname):]] = child
+			delete(m.nodes, p)
+		}
+	}
+	delete(m.nodes, oldname)
+	for p, node := range moved {
+		m.nodes[p] = node
+	}
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode&^fs.ModePerm | mode.Perm()
+	return nil
+}
+
+func (m *MemFS) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.lookup("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.modTime = mtime
+	return nil
+}
+
+// Symlink always fails with errors.ErrUnsupported.
+func (m *MemFS) Symlink(oldname, newname string) error {
+	return memError("symlink", newname, errors.ErrUnsupported)
+}
+
+func (n *memNode) info(name string) fs.FileInfo {
+	return &memInfo{name: path.Base(name), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
+
+// memFile is an open file or directory of a MemFS.
+type memFile struct {
+	fsys     *MemFS
+	node     *memNode
+	name     string
+	offset   int64
+	readable bool
+	writable bool
+	append   bool
+	closed   bool
+	entries  []fs.DirEntry
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	return f.node.info(f.name), nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	switch {
+	case f.closed:
+		return 0, memError("read", f.name, fs.ErrClosed)
+	case f.node.mode.IsDir():
+		return 0, memError("read", f.name, errIsDir)
+	case !f.readable:
+		return 0, memError("read", f.name, fs.ErrPermission)
+	case f.offset >= int64(len(f.node.data)):
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	if f.closed {
+		return 0, memError("read", f.name, fs.ErrClosed)
+	}
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	switch {
+	case f.closed:
+		return 0, memError("write", f.name, fs.ErrClosed)
+	case !f.writable:
+		return 0, memError("write", f.name, fs.ErrPermission)
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset += int64(len(p))
+	f.node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.RLock()
+	defer f.fsys.mu.RUnlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, memError("seek", f.name, fs.ErrInvalid)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.node.mode.IsDir() {
+		return nil, memError("readdir", f.name, errNotDir)
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *memFile) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return memError("close", f.name, fs.ErrClosed)
+	}
+	f.closed = true
+	return nil
+}
@@ -0,0 +1,382 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Overlay returns an FS that layers the writable upper over the read-only lower, like a union mount.
+// Reads see the files of upper, then those of lower. Writes go to upper: modifying a file of lower
+// first copies it up, and removing it hides it without touching lower.
+// The hidden files are only remembered by the returned FS, not persisted in upper.
+//
+// 叠加文件系统：读取时优先读上层，写入只发生在上层，下层保持只读
+// 示例:
+//
+//	//go:embed defaults
+//	var defaults embed.FS
+//
+//	fsys := file.Overlay(defaults, file.NewMemFS())
+func Overlay(lower fs.FS, upper FS) FS {
+	return &overlayFS{lower: lower, upper: upper, hidden: map[string]bool{}}
+}
+
+type overlayFS struct {
+	lower fs.FS
+	upper FS
+	mu    sync.Mutex
+	// hidden are the names removed from lower, together with everything below them.
+	hidden map[string]bool
+}
+
+// lowerVisible reports whether name of lower is visible, i.e. neither it nor a parent was removed.
+func (o *overlayFS) lowerVisible(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for p := name; ; p = path.Dir(p) {
+		if o.hidden[p] {
+			return false
+		}
+		if p == "." {
+			return true
+		}
+	}
+}
+
+func (o *overlayFS) hide(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.hidden[name] = true
+}
+
+// inUpper reports whether name exists in upper.
+func (o *overlayFS) inUpper(name string) bool {
+	_, err := o.upper.Lstat(name)
+	return err == nil
+}
+
+// inLower reports whether name exists in lower and is visible.
+func (o *overlayFS) inLower(name string) bool {
+	if !o.lowerVisible(name) {
+		return false
+	}
+	_, err := lstat(o.lower, name)
+	return err == nil
+}
+
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var (
+		f   fs.File
+		err error
+	)
+	if o.inUpper(name) || !o.lowerVisible(name) {
+		f, err = o.upper.Open(name)
+	} else {
+		f, err = o.lower.Open(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		// 目录需要合并两层的内容
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &overlayDir{File: f, entries: entries}, nil
+	}
+	return f, nil
+}
+
+// overlayDir is an open directory of an overlayFS, listing the merged entries.
+type overlayDir struct {
+	fs.File
+	entries []fs.DirEntry
+}
+
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (o *overlayFS) Stat(name string) (fs.FileInfo, error) {
+	if o.inUpper(name) || !o.lowerVisible(name) {
+		return o.upper.Stat(name)
+	}
+	return fs.Stat(o.lower, name)
+}
+
+func (o *overlayFS) Lstat(name string) (fs.FileInfo, error) {
+	if o.inUpper(name) || !o.lowerVisible(name) {
+		return o.upper.Lstat(name)
+	}
+	return lstat(o.lower, name)
+}
+
+func (o *overlayFS) Readlink(name string) (string, error) {
+	if o.inUpper(name) || !o.lowerVisible(name) {
+		return o.upper.Readlink(name)
+	}
+	return readlink(o.lower, name)
+}
+
+// ReadDir merges the entries of both layers, those of upper taking precedence.
+func (o *overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, err := o.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+
+	merged := map[string]fs.DirEntry{}
+	if o.lowerVisible(name) {
+		if entries, err := fs.ReadDir(o.lower, name); err == nil {
+			for _, e := range entries {
+				if o.lowerVisible(path.Join(name, e.Name())) {
+					merged[e.Name()] = e
+				}
+			}
+		}
+	}
+	if entries, err := o.upper.ReadDir(name); err == nil {
+		for _, e := range entries {
+			merged[e.Name()] = e
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	result := make([]fs.DirEntry, 0, len(merged))
+	for _, e := range merged {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+	return result, nil
+}
+
+// copyUp copies name and its parent directories from lower to upper, unless they already exist in upper.
+// With deep, the content of a directory is copied as well, recursively.
+func (o *overlayFS) copyUp(name string, deep bool) error {
+	info, err := o.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if name != "." && !o.inUpper(name) {
+		if err := o.copyUp(path.Dir(name), false); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case info.IsDir():
+		if !o.inUpper(name) {
+			if err := o.upper.Mkdir(name, info.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+			defer o.upper.Chmod(name, info.Mode().Perm())
+		}
+		if !deep {
+			return nil
+		}
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := o.copyUp(path.Join(name, e.Name()), true); err != nil {
+				return err
+			}
+		}
+		return nil
+	case o.inUpper(name):
+		return nil
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := readlink(o.lower, name)
+		if err != nil {
+			return err
+		}
+		return o.upper.Symlink(target, name)
+	default:
+		src, err := o.lower.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := o.upper.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(dst, src); err != nil {
+			_ = dst.Close()
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+		return o.upper.Chtimes(name, info.ModTime(), info.ModTime())
+	}
+}
+
+// prepare makes name writable in upper: an existing file is copied up, a new one gets its parent directories.
+func (o *overlayFS) prepare(name string) error {
+	if _, err := o.Lstat(name); err == nil {
+		return o.copyUp(name, false)
+	}
+	return o.copyUp(path.Dir(name), false)
+}
+
+func (o *overlayFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		if o.inUpper(name) || !o.lowerVisible(name) {
+			return o.upper.OpenFile(name, flag, perm)
+		}
+		return ReadOnly(o.lower).OpenFile(name, flag, perm)
+	}
+	if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+		if _, err := o.Lstat(name); err == nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+	}
+	if err := o.prepare(name); err != nil {
+		return nil, err
+	}
+	return o.upper.OpenFile(name, flag, perm)
+}
+
+func (o *overlayFS) Mkdir(name string, perm fs.FileMode) error {
+	if _, err := o.Lstat(name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := o.copyUp(path.Dir(name), false); err != nil {
+		return err
+	}
+	return o.upper.Mkdir(name, perm)
+}
+
+func (o *overlayFS) MkdirAll(name string, perm fs.FileMode) error {
+	if info, err := o.Stat(name); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: errNotDir}
+	}
+	// 复制已存在的最深一级父目录，再在上层创建其余部分
+	parent := path.Dir(name)
+	for {
+		if _, err := o.Lstat(parent); err == nil || parent == "." {
+			break
+		}
+		parent = path.Dir(parent)
+	}
+	if err := o.copyUp(parent, false); err != nil {
+		return err
+	}
+	return o.upper.MkdirAll(name, perm)
+}
+
+func (o *overlayFS) Remove(name string) error {
+	info, err := o.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+		}
+	}
+	if o.inUpper(name) {
+		if err := o.upper.Remove(name); err != nil {
+			return err
+		}
+	}
+	if o.inLower(name) {
+		o.hide(name)
+	}
+	return nil
+}
+
+func (o *overlayFS) RemoveAll(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+	if err := o.upper.RemoveAll(name); err != nil {
+		return err
+	}
+	if o.inLower(name) {
+		o.hide(name)
+	}
+	return nil
+}
+
+func (o *overlayFS) Rename(oldname, newname string) error {
+	if _, err := o.Lstat(oldname); err != nil {
+		return err
+	}
+	if err := o.copyUp(oldname, true); err != nil {
+		return err
+	}
+	if err := o.prepare(newname); err != nil {
+		return err
+	}
+	// 上层的目标将被替换，下层同名的内容不能再透出
+	if o.inLower(newname) {
+		o.hide(newname)
+	}
+	if err := o.upper.Rename(oldname, newname); err != nil {
+		return err
+	}
+	if o.inLower(oldname) {
+		o.hide(oldname)
+	}
+	return nil
+}
+
+func (o *overlayFS) Chmod(name string, mode fs.FileMode) error {
+	if err := o.copyUp(name, false); err != nil {
+		return err
+	}
+	return o.upper.Chmod(name, mode)
+}
+
+func (o *overlayFS) Chtimes(name string, atime, mtime time.Time) error {
+	if err := o.copyUp(name, false); err != nil {
+		return err
+	}
+	return o.upper.Chtimes(name, atime, mtime)
+}
+
+func (o *overlayFS) Symlink(oldname, newname string) error {
+	if _, err := o.Lstat(newname); err == nil {
+		return &fs.PathError{Op: "symlink", Path: newname, Err: fs.ErrExist}
+	}
+	if err := o.copyUp(path.Dir(newname), false); err != nil {
+		return err
+	}
+	return o.upper.Symlink(oldname, newname)
+}
@@ -0,0 +1,259 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrPathEscapes is returned when a path would resolve outside of its base directory.
+var ErrPathEscapes = errors.New("path escapes from the base directory")
+
+// SecureJoin joins the untrusted, possibly user supplied, path onto base and guarantees that the
+// result is inside base: ".." components that climb out of base and symlinks that resolve outside
+// of it fail with ErrPathEscapes. Missing components are allowed, so the result can be used to create files.
+// Absolute untrusted paths are taken as relative to base.
+//
+// A symlink created after the check can still redirect the path, so do not use it on directories
+// writable by untrusted users.
+//
+// 安全地拼接不可信的路径，拒绝通过..或符号链接逃出base目录
+// 示例:
+//
+//	p, err := file.SecureJoin("/srv/uploads", r.FormValue("name"))
+//	if errors.Is(err, file.ErrPathEscapes) {
+//		http.Error(w, "invalid name", http.StatusBadRequest)
+//	}
+func SecureJoin(base, untrusted string) (string, error) {
+	if filepath.VolumeName(untrusted) != "" || strings.ContainsRune(untrusted, 0) {
+		return "", &os.PathError{Op: "securejoin", Path: untrusted, Err: ErrPathEscapes}
+	}
+	p := filepath.Join(base, filepath.FromSlash(untrusted))
+	if !isWithin(base, p) {
+		return "", &os.PathError{Op: "securejoin", Path: untrusted, Err: ErrPathEscapes}
+	}
+
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+	real, err := evalExisting(p, 0)
+	if err != nil {
+		return "", err
+	}
+	if !isWithin(realBase, real) {
+		return "", &os.PathError{Op: "securejoin", Path: untrusted, Err: ErrPathEscapes}
+	}
+	return p, nil
+}
+
+// IsSubPath reports whether path is base or inside base, comparing absolute, cleaned paths.
+// Symlinks are not resolved, use SecureJoin for untrusted input.
+//
+// 判断path是否在base目录内
+func IsSubPath(base, path string) bool {
+	return isWithin(base, path)
+}
+
+// RelPath returns the path of target relative to base, making both absolute first,
+// so that it also works when one is relative and the other absolute.
+//
+// 计算target相对于base的路径
+// 示例:
+//
+//	rel, _ := file.RelPath("/a/b", "/a/c/d.txt") // "../c/d.txt"
+func RelPath(base, target string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, absTarget)
+}
+
+// ExpandHome replaces a leading "~" with the home directory of the current user,
+// and "~name" with the home directory of the user name.
+//
+// 展开开头的~为用户主目录
+func ExpandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	name, rest := path[1:], ""
+	if i := strings.IndexAny(name, `/\`); i >= 0 && (name[i] == '/' || runtime.GOOS == "windows") {
+		name, rest = name[:i], name[i:]
+	}
+
+	var home string
+	if name == "" {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		home = dir
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		home = u.HomeDir
+	}
+	return home + rest, nil
+}
+
+// ExpandPath expands a leading "~" like ExpandHome and environment variables written as $VAR or ${VAR},
+// and on Windows also %VAR%. Undefined variables expand to the empty string, like in a shell.
+//
+// 展开~和环境变量
+// 示例:
+//
+//	p, err := file.ExpandPath("~/.config/$APP_NAME/config.yaml")
+func ExpandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if runtime.GOOS == "windows" {
+		path = expandPercentVars(path)
+	}
+	return ExpandHome(path)
+}
+
+// expandPercentVars expands the %VAR% variables of cmd.exe, leaving undefined ones as they are.
+func expandPercentVars(s string) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(s, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		name := s[start+1 : end]
+		if value, ok := os.LookupEnv(name); ok && name != "" {
+			sb.WriteString(s[:start])
+			sb.WriteString(value)
+		} else {
+			sb.WriteString(s[:end])
+			s = s[end:]
+			continue
+		}
+		s = s[end+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// ToPosixPath converts a Windows path to the form used by MSYS2, Git Bash and Cygwin shells:
+// backslashes become slashes and a drive letter becomes a root directory, e.g. C:\Users\me to /c/Users/me.
+// UNC paths keep their double slash. It works the same on every OS.
+//
+// 将Windows路径转换为POSIX形式，如C:\Users\me转换为/c/Users/me
+func ToPosixPath(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if len(p) >= 2 && p[1] == ':' && isDriveLetter(p[0]) {
+		rest := p[2:]
+		if rest != "" && rest[0] != '/' {
+			// C:foo是相对于驱动器当前目录的路径，无法精确转换
+			rest = "/" + rest
+		}
+		p = "/" + strings.ToLower(p[:1]) + rest
+	}
+	return p
+}
+
+// ToWindowsPath converts a POSIX path to Windows form: slashes become backslashes, and /c/... as well as
+// the WSL form /mnt/c/... become C:\.... It works the same on every OS.
+//
+// 将POSIX路径转换为Windows形式，如/c/Users/me或/mnt/c/Users/me转换为C:\Users\me
+func ToWindowsPath(p string) string {
+	for _, prefix := range []string{"/mnt/", "/"} {
+		rest, ok := strings.CutPrefix(p, prefix)
+		if !ok || len(rest) == 0 || !isDriveLetter(rest[0]) || (len(rest) > 1 && rest[1] != '/') {
+			continue
+		}
+		p = strings.ToUpper(rest[:1]) + ":" + rest[1:]
+		if len(rest) == 1 {
+			p += "/"
+		}
+		break
+	}
+	return strings.ReplaceAll(p, "/", `\`)
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// windowsReserved are the device names Windows refuses as file names, with or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// maxFilenameBytes is the name length limit of most filesystems.
+const maxFilenameBytes = 255
+
+// SanitizeFilename turns name into a file name that is valid on Windows, macOS and Linux:
+// path separators, the characters <>:"|?* and control characters are replaced by "_",
+// trailing dots and spaces are removed, reserved device names such as CON get a "_" prefix,
+// and names longer than 255 bytes are shortened, keeping the extension and valid UTF-8.
+// An empty result, "." and ".." become "_".
+//
+// 将任意字符串转换为跨平台安全的文件名
+// 示例:
+//
+//	file.SanitizeFilename(`report: 2024/05?.pdf`) // "report_ 2024_05_.pdf"
+func SanitizeFilename(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToValidUTF8(name, "_") {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			sb.WriteByte('_')
+		case strings.ContainsRune(`<>:"/\|?*`, r):
+			sb.WriteByte('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	name = strings.TrimRight(sb.String(), ". ")
+	if name == "" {
+		return "_"
+	}
+
+	stem, _, _ := strings.Cut(name, ".")
+	if windowsReserved[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		name = "_" + name
+	}
+
+	if len(name) > maxFilenameBytes {
+		ext := filepath.Ext(name)
+		if len(ext) > maxFilenameBytes/2 {
+			ext = ""
+		}
+		stem := truncateUTF8(name[:len(name)-len(ext)], maxFilenameBytes-len(ext))
+		name = strings.TrimRight(stem, ". ") + ext
+	}
+	return name
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
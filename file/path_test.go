@@ -0,0 +1,181 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSecureJoin(t *testing.T) {
+	base := makeTree(t, map[string]string{"a/b.txt": "b"})
+
+	tests := []struct {
+		untrusted string
+		want      string
+	}{
+		{"a/b.txt", "a/b.txt"},
+		{"a/../a/new.txt", "a/new.txt"},
+		{"/etc/passwd", "etc/passwd"},
+		{"missing/dir/file", "missing/dir/file"},
+		{"", "."},
+	}
+	for _, tt := range tests {
+		got, err := SecureJoin(base, tt.untrusted)
+		if err != nil {
+			t.Errorf("SecureJoin(%q) error = %v", tt.untrusted, err)
+			continue
+		}
+		if want := filepath.Join(base, filepath.FromSlash(tt.want)); got != want {
+			t.Errorf("SecureJoin(%q) = %q, want %q", tt.untrusted, got, want)
+		}
+	}
+
+	for _, untrusted := range []string{"..", "../x", "a/../../x", "a\x00b"} {
+		if _, err := SecureJoin(base, untrusted); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("SecureJoin(%q) error = %v, want ErrPathEscapes", untrusted, err)
+		}
+	}
+}
+
+func TestSecureJoinSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	base := makeTree(t, map[string]string{"dir/f.txt": "f"})
+	outside := t.TempDir()
+	for link, target := range map[string]string{
+		"inner":    "dir",
+		"abs":      outside,
+		"up":       "..",
+		"dangling": filepath.Join(outside, "missing"),
+	} {
+		if err := os.Symlink(target, filepath.Join(base, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := SecureJoin(base, "inner/f.txt"); err != nil {
+		t.Errorf("SecureJoin(inner/f.txt) error = %v", err)
+	}
+	for _, untrusted := range []string{"abs/x", "up/x", "dangling", "inner/../up"} {
+		if _, err := SecureJoin(base, untrusted); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("SecureJoin(%q) error = %v, want ErrPathEscapes", untrusted, err)
+		}
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	root := t.TempDir()
+	got, err := RelPath(filepath.Join(root, "a", "b"), filepath.Join(root, "a", "c", "d.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.FromSlash("../c/d.txt"); got != want {
+		t.Errorf("RelPath() = %q, want %q", got, want)
+	}
+
+	wd, _ := os.Getwd()
+	got, err = RelPath(wd, "x/y")
+	if err != nil || got != filepath.FromSlash("x/y") {
+		t.Errorf("RelPath(abs, rel) = %q, %v", got, err)
+	}
+
+	if !IsSubPath(root, filepath.Join(root, "a")) || IsSubPath(filepath.Join(root, "a"), filepath.Join(root, "ab")) {
+		t.Error("IsSubPath() gave a wrong answer")
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Setenv("GOUTILS_APP", "demo")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"~", home},
+		{"~/x", home + "/x"},
+		{"$GOUTILS_APP/config", "demo/config"},
+		{"${GOUTILS_APP}.yaml", "demo.yaml"},
+		{"~/$GOUTILS_APP", home + "/demo"},
+		{"plain/~", "plain/~"},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, struct{ in, want string }{`%GOUTILS_APP%\x`, `demo\x`})
+		tests = append(tests, struct{ in, want string }{`%GOUTILS_UNSET%`, `%GOUTILS_UNSET%`})
+	}
+	for _, tt := range tests {
+		got, err := ExpandPath(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := ExpandHome("~no-such-user-goutils/x"); err == nil {
+		t.Error("ExpandHome(unknown user) error = nil")
+	}
+}
+
+func TestPathConversion(t *testing.T) {
+	toPosix := map[string]string{
+		`C:\Users\me`:      "/c/Users/me",
+		`d:/data`:          "/d/data",
+		`C:`:               "/c",
+		`\\server\share\x`: "//server/share/x",
+		`rel\dir`:          "rel/dir",
+	}
+	for in, want := range toPosix {
+		if got := ToPosixPath(in); got != want {
+			t.Errorf("ToPosixPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	toWindows := map[string]string{
+		"/c/Users/me":      `C:\Users\me`,
+		"/mnt/d/data":      `D:\data`,
+		"/c":               `C:\`,
+		"//server/share/x": `\\server\share\x`,
+		"/usr/bin":         `\usr\bin`,
+		"rel/dir":          `rel\dir`,
+	}
+	for in, want := range toWindows {
+		if got := ToWindowsPath(in); got != want {
+			t.Errorf("ToWindowsPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{`report: 2024/05?.pdf`, "report_ 2024_05_.pdf"},
+		{"a\x00b\tc", "a_b_c"},
+		{"trailing. . ", "trailing"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"console", "console"},
+		{"", "_"},
+		{"..", "_"},
+		{"中文名.txt", "中文名.txt"},
+		{"bad\xffutf8", "bad_utf8"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("文", 100) + ".txt"
+	got := SanitizeFilename(long)
+	if len(got) > 255 || !strings.HasSuffix(got, ".txt") || !utf8.ValidString(got) {
+		t.Errorf("SanitizeFilename(long) = %q (%d bytes)", got, len(got))
+	}
+}
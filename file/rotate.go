@@ -0,0 +1,381 @@
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mudssky/goutils"
+)
+
+// backupTimeFormat is the timestamp in the names of rotated files, without colons for Windows.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOptions configures a RotatingWriter.
+//
+// 日志轮转的配置项
+type RotateOptions struct {
+	// MaxSize rotates the file before a write would make it larger. 0 disables rotation by size.
+	// A single write larger than MaxSize still goes to one file.
+	MaxSize ByteSize
+	// Interval rotates the file at the first write after each period of the given length, aligned on
+	// local time, e.g. 24*time.Hour rotates daily at midnight. 0 disables rotation by time.
+	Interval time.Duration
+	// MaxBackups keeps at most this many rotated files, deleting the oldest. 0 keeps all.
+	MaxBackups int
+	// MaxAge deletes rotated files older than this. 0 keeps them forever.
+	MaxAge time.Duration
+	// Compress gzips rotated files in the background.
+	Compress bool
+	// Perm is the permission of new files, 0o644 by default.
+	Perm os.FileMode
+	// Clock is used for rotation times and backup names, goutils.SystemClock by default.
+	Clock goutils.Clock
+	// OnError is called with the errors of the background compression and cleanup, which are ignored by default.
+	OnError func(err error)
+}
+
+// RotatingWriter is an io.WriteCloser for log files that rotates them by size and/or time.
+// Rotated files are renamed with their rotation time, e.g. app.log becomes app-2024-05-01T00-00-00.000.log
+// (or .log.gz when compressed), and a new app.log is started. It is safe for concurrent use.
+//
+// 按大小和/或时间轮转的日志文件写入器，可并发使用
+// 示例:
+//
+//	w, err := file.NewRotatingWriter("logs/app.log", file.RotateOptions{
+//		MaxSize:    100 * file.MiB,
+//		Interval:   24 * time.Hour,
+//		MaxBackups: 7,
+//		Compress:   true,
+//	})
+//	log.SetOutput(w)
+type RotatingWriter struct {
+	path  string
+	opts  RotateOptions
+	clock goutils.Clock
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	next   time.Time
+	closed bool
+
+	millCh   chan struct{}
+	millDone chan struct{}
+}
+
+// NewRotatingWriter opens path for appending, creating it and its directory if needed.
+// An existing file is continued, and rotated at the first write if its period is already over.
+//
+// 创建轮转写入器
+func NewRotatingWriter(path string, opts RotateOptions) (*RotatingWriter, error) {
+	if opts.Perm == 0 {
+		opts.Perm = 0o644
+	}
+	w := &RotatingWriter{path: path, opts: opts, clock: opts.Clock}
+	if w.clock == nil {
+		w.clock = goutils.SystemClock
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	if opts.Compress || opts.MaxBackups > 0 || opts.MaxAge > 0 {
+		w.millCh = make(chan struct{}, 1)
+		w.millDone = make(chan struct{})
+		go w.mill()
+		// 清理上次运行留下的旧文件
+		w.millCh <- struct{}{}
+	}
+	return w, nil
+}
+
+// Path returns the path of the current file.
+func (w *RotatingWriter) Path() string {
+	return w.path
+}
+
+// Write writes p to the current file, rotating it first if needed.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	now := w.clock.Now()
+	overSize := w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > int64(w.opts.MaxSize)
+	overTime := !w.next.IsZero() && !now.Before(w.next)
+	if overSize || overTime {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it to a backup and starts a new one,
+// whatever its size and age. An empty file is rotated as well.
+//
+// 立即轮转
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file at path without renaming it, for use after an external tool
+// like logrotate moved it away, typically on SIGHUP.
+//
+// 重新打开文件，用于外部工具移走日志文件之后
+// 示例:
+//
+//	hup := make(chan os.Signal, 1)
+//	signal.Notify(hup, syscall.SIGHUP)
+//	go func() {
+//		for range hup {
+//			w.Reopen()
+//		}
+//	}()
+func (w *RotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Sync commits the current file to stable storage.
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.f.Sync()
+}
+
+// Close closes the current file and waits for the background compression and cleanup to finish.
+// Calling Close again does nothing.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.f.Close()
+	w.mu.Unlock()
+
+	if w.millCh != nil {
+		close(w.millCh)
+		<-w.millDone
+	}
+	return err
+}
+
+// open opens the file at path for appending and computes when it must be rotated.
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, w.opts.Perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	w.next = time.Time{}
+	if w.opts.Interval > 0 {
+		start := w.clock.Now()
+		if w.size > 0 && info.ModTime().Before(start) {
+			start = info.ModTime()
+		}
+		w.next = nextPeriod(start, w.opts.Interval)
+	}
+	return nil
+}
+
+// nextPeriod returns the start of the period after the one containing t, with periods aligned on local time.
+func nextPeriod(t time.Time, interval time.Duration) time.Time {
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(interval).Add(interval).Add(-shift)
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	backup := w.backupName(w.clock.Now())
+	if err := os.Rename(w.path, backup); err != nil && !os.IsNotExist(err) {
+		// 重命名失败时继续写原文件，不丢失日志
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if w.millCh != nil {
+		select {
+		case w.millCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// backupName returns an unused name for a backup rotated at t.
+func (w *RotatingWriter) backupName(t time.Time) string {
+	dir, base := filepath.Split(w.path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for {
+		name := filepath.Join(dir, stem+"-"+t.Format(backupTimeFormat)+ext)
+		if !existsAny(name, name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func existsAny(paths ...string) bool {
+	for _, p := range paths {
+		if _, err := os.Lstat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// backupFile is a rotated file found by backups.
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups lists the rotated files of w, newest first.
+func (w *RotatingWriter) backups() ([]backupFile, error) {
+	dir, base := filepath.Split(w.path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		ts, ok := strings.CutPrefix(name, stem+"-")
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		ts = strings.TrimSuffix(ts, ".gz")
+		if ts, ok = strings.CutSuffix(ts, ext); !ok {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, ts, w.clock.Now().Location())
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].time.After(files[j].time) })
+	return files, nil
+}
+
+// mill compresses and deletes rotated files in the background each time it is signalled.
+func (w *RotatingWriter) mill() {
+	defer close(w.millDone)
+	for range w.millCh {
+		if err := w.millOnce(); err != nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+	}
+}
+
+func (w *RotatingWriter) millOnce() error {
+	files, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	cutoff := w.clock.Now().Add(-w.opts.MaxAge)
+	for i, b := range files {
+		expired := (w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups) || (w.opts.MaxAge > 0 && b.time.Before(cutoff))
+		switch {
+		case expired:
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		case w.opts.Compress && !strings.HasSuffix(b.path, ".gz"):
+			if err := gzipFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// gzipFile compresses path to path+".gz" and removes path.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := NewAtomicWriter(path+".gz", info.Mode().Perm(), AtomicOptions{})
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = info.ModTime()
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = w.Abort()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = w.Abort()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(path+".gz", info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,237 @@
+package file
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mudssky/goutils"
+)
+
+// listDir returns the sorted names in dir.
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func write(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+	if _, err := io.WriteString(w, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotatingWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := goutils.NewFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local))
+	p := filepath.Join(dir, "app.log")
+	w, err := NewRotatingWriter(p, RotateOptions{MaxSize: 10, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, w, "12345")
+	write(t, w, "67890")
+	clock.Advance(time.Second)
+	write(t, w, "abc") // 超过10字节，先轮转
+	write(t, w, "this write is larger than MaxSize")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"app-2024-05-01T10-00-01.000.log", "app-2024-05-01T10-00-01.001.log", "app.log"}
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	assertFileContent(t, filepath.Join(dir, want[0]), "1234567890")
+	assertFileContent(t, filepath.Join(dir, want[1]), "abc")
+	assertFileContent(t, p, "this write is larger than MaxSize")
+
+	if _, err := w.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write() after Close error = %v", err)
+	}
+}
+
+func TestRotatingWriterInterval(t *testing.T) {
+	dir := t.TempDir()
+	clock := goutils.NewFakeClock(time.Date(2024, 5, 1, 23, 30, 0, 0, time.Local))
+	p := filepath.Join(dir, "app.log")
+	w, err := NewRotatingWriter(p, RotateOptions{Interval: 24 * time.Hour, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "day1\n")
+	clock.Advance(20 * time.Minute)
+	write(t, w, "day1 again\n")
+	clock.Advance(20 * time.Minute) // 00:10，进入新的一天
+	write(t, w, "day2\n")
+	w.Close()
+
+	want := []string{"app-2024-05-02T00-10-00.000.log", "app.log"}
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	assertFileContent(t, filepath.Join(dir, want[0]), "day1\nday1 again\n")
+
+	// 重新打开时，上一周期写入的文件在第一次写入时轮转
+	old := time.Date(2024, 5, 2, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(time.Date(2024, 5, 3, 8, 0, 0, 0, time.Local))
+	w, err = NewRotatingWriter(p, RotateOptions{Interval: 24 * time.Hour, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "day3\n")
+	w.Close()
+	assertFileContent(t, p, "day3\n")
+	assertFileContent(t, filepath.Join(dir, "app-2024-05-03T08-00-00.000.log"), "day2\n")
+}
+
+func TestRotatingWriterCleanup(t *testing.T) {
+	dir := t.TempDir()
+	clock := goutils.NewFakeClock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local))
+	p := filepath.Join(dir, "app.log")
+	// 无关的文件不能被删除
+	if err := os.WriteFile(filepath.Join(dir, "app-notes.log"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	w, err := NewRotatingWriter(p, RotateOptions{
+		MaxBackups: 2,
+		MaxAge:     72 * time.Hour,
+		Compress:   true,
+		Clock:      clock,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 4; i++ {
+		write(t, w, fmt.Sprintf("file %d\n", i))
+		clock.Advance(time.Hour)
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("OnError called with %v", errs)
+	}
+
+	want := []string{"app-2024-05-01T03-00-00.000.log.gz", "app-2024-05-01T04-00-00.000.log.gz", "app-notes.log", "app.log"}
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	f, err := os.Open(filepath.Join(dir, want[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != "file 4\n" {
+		t.Errorf("compressed backup = %q", data)
+	}
+
+	// MaxAge在下次启动时删除过期的备份
+	clock.Advance(72 * time.Hour)
+	w, err = NewRotatingWriter(p, RotateOptions{MaxAge: 72 * time.Hour, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	want = []string{"app-2024-05-01T04-00-00.000.log.gz", "app-notes.log", "app.log"}
+	if got := listDir(t, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("files after MaxAge = %v, want %v", got, want)
+	}
+}
+
+func TestRotatingWriterConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	w, err := NewRotatingWriter(p, RotateOptions{MaxSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.Repeat("x", 99) + "\n"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := w.Write([]byte(line)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	w.Close()
+
+	var total int64
+	for _, name := range listDir(t, dir) {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 1000 || info.Size()%100 != 0 {
+			t.Errorf("%s has size %d", name, info.Size())
+		}
+		total += info.Size()
+	}
+	if total != 8*50*100 {
+		t.Errorf("total size = %d, want %d", total, 8*50*100)
+	}
+}
+
+func TestRotatingWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	w, err := NewRotatingWriter(p, RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	write(t, w, "before\n")
+	// 模拟logrotate移走文件
+	if err := os.Rename(p, p+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "after\n")
+	assertFileContent(t, p+".1", "before\n")
+	assertFileContent(t, p, "after\n")
+}
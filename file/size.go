@@ -0,0 +1,142 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// DiskUsage is the space of a filesystem.
+//
+// 文件系统的空间使用情况
+type DiskUsage struct {
+	// Total is the size of the filesystem.
+	Total ByteSize
+	// Free is the unused space, including blocks reserved for the superuser.
+	Free ByteSize
+	// Available is the space unprivileged users can still write.
+	Available ByteSize
+}
+
+// Used returns the space in use, Total minus Free.
+func (u DiskUsage) Used() ByteSize {
+	return u.Total - u.Free
+}
+
+// Usage reports the total, free and available space of the filesystem (or Windows volume) holding path.
+// It uses statfs on Linux, macOS and FreeBSD and GetDiskFreeSpaceEx on Windows; elsewhere it
+// returns errors.ErrUnsupported.
+//
+// 获取path所在文件系统的总空间和剩余空间
+// 示例:
+//
+//	u, err := file.Usage("/var/log")
+//	if u.Available < 1*file.GiB {
+//		log.Printf("low disk space: %v left", u.Available)
+//	}
+func Usage(path string) (DiskUsage, error) {
+	u, err := diskUsage(path)
+	if err != nil {
+		return DiskUsage{}, &os.PathError{Op: "usage", Path: path, Err: err}
+	}
+	return u, nil
+}
+
+// DirSize returns the total size of the regular files below path, or the size of path itself
+// if it is a file. Directories are read in parallel. Symlinks are not followed (except path itself),
+// and files with several hard links inside the tree are counted once, except on Windows.
+//
+// Unreadable entries do not stop the traversal: the sizes of everything readable are summed up
+// and the first error is returned with them.
+//
+// 并行计算目录下所有普通文件的总大小，硬链接只计算一次
+// 示例:
+//
+//	size, err := file.DirSize("node_modules")
+//	fmt.Println(size) // 312.4 MiB
+func DirSize(path string) (ByteSize, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return ByteSize(info.Size()), nil
+	}
+
+	s := &sizer{sem: make(chan struct{}, runtime.GOMAXPROCS(0)*2), seen: map[[2]uint64]bool{}}
+	s.wg.Add(1)
+	s.dir(path)
+	s.wg.Wait()
+	return ByteSize(s.total.Load()), s.err
+}
+
+// sizer sums up file sizes, reading directories in goroutines limited by sem.
+type sizer struct {
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	total atomic.Int64
+
+	mu   sync.Mutex
+	seen map[[2]uint64]bool
+	err  error
+}
+
+// dir adds the sizes below dir and calls wg.Done when finished.
+func (s *sizer) dir(dir string) {
+	defer s.wg.Done()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.fail(err)
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir():
+			s.wg.Add(1)
+			select {
+			case s.sem <- struct{}{}:
+				go func() {
+					defer func() { <-s.sem }()
+					s.dir(p)
+				}()
+			default:
+				// 没有空闲的并发额度时在当前goroutine中处理，避免死锁
+				s.dir(p)
+			}
+		case e.Type().IsRegular():
+			info, err := e.Info()
+			if err != nil {
+				if !os.IsNotExist(err) {
+					s.fail(err)
+				}
+				continue
+			}
+			if dev, ino, linked := fileID(info); linked && !s.first(dev, ino) {
+				continue
+			}
+			s.total.Add(info.Size())
+		}
+	}
+}
+
+// first reports whether the file with the given device and inode is seen for the first time.
+func (s *sizer) first(dev, ino uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]uint64{dev, ino}
+	if s.seen[key] {
+		return false
+	}
+	s.seen[key] = true
+	return true
+}
+
+func (s *sizer) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,65 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDirSize(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.txt":       "hello",
+		"sub/b.txt":   strings.Repeat("x", 100),
+		"sub/c/d.txt": "123",
+	})
+	got, err := DirSize(root)
+	if err != nil {
+		t.Fatalf("DirSize() error = %v", err)
+	}
+	if got != 108 {
+		t.Errorf("DirSize() = %d, want 108", got)
+	}
+
+	if got, err := DirSize(filepath.Join(root, "a.txt")); err != nil || got != 5 {
+		t.Errorf("DirSize(file) = %d, %v, want 5", got, err)
+	}
+	if _, err := DirSize(filepath.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Errorf("DirSize(missing) error = %v", err)
+	}
+}
+
+func TestDirSizeLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are counted separately on windows")
+	}
+	root := makeTree(t, map[string]string{"a.txt": strings.Repeat("x", 1000)})
+	if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "hard.txt")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(t.TempDir(), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "link", "big"), make([]byte, 5000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DirSize(root)
+	if err != nil || got != 1000 {
+		t.Errorf("DirSize() = %d, %v, want 1000", got, err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	u, err := Usage(t.TempDir())
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if u.Total <= 0 || u.Free > u.Total || u.Available > u.Free || u.Used() < 0 {
+		t.Errorf("Usage() = %+v", u)
+	}
+	if _, err := Usage(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Usage(missing) error = nil")
+	}
+}
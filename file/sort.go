@@ -0,0 +1,472 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// SortOrder is the order of the lines, or of their keys, compared by SortLines.
+//
+// 行的排序方式
+type SortOrder int
+
+const (
+	// SortLexical compares lines byte by byte, which is the order of code points for UTF-8.
+	SortLexical SortOrder = iota
+	// SortNumeric compares the number at the start of the lines, e.g. "9 b" before "10 a", like sort -n.
+	// Leading white space is ignored and lines without a number sort first.
+	SortNumeric
+	// SortNatural compares runs of digits by their value and the rest byte by byte,
+	// e.g. "file2.txt" before "file10.txt".
+	SortNatural
+)
+
+func (o SortOrder) String() string {
+	switch o {
+	case SortLexical:
+		return "lexical"
+	case SortNumeric:
+		return "numeric"
+	case SortNatural:
+		return "natural"
+	}
+	return "SortOrder(" + strconv.Itoa(int(o)) + ")"
+}
+
+const (
+	defaultSortMemory     = 64 * MiB
+	defaultSortMergeWidth = 64
+	// sortLineOverhead is the estimated memory used per line besides its bytes.
+	sortLineOverhead = 64
+)
+
+// SortOptions configures SortLines.
+//
+// 外部排序的配置项
+type SortOptions struct {
+	// Order is how lines, or their keys, are compared when Compare is nil. SortLexical by default.
+	Order SortOrder
+	// Compare, if set, compares the keys of two lines instead of Order, returning a negative number,
+	// zero or a positive number like bytes.Compare.
+	Compare func(a, b []byte) int
+	// Key, if set, extracts the part of a line that is compared, e.g. a column. The whole line by default.
+	// It may return a subslice of line, which must not be modified.
+	Key func(line []byte) []byte
+	// Reverse sorts in descending order.
+	Reverse bool
+	// Unique keeps only the first line of the input among the lines whose keys compare equal.
+	Unique bool
+	// MaxMemory is the approximate memory used for the lines of a sorted run, 64 MiB by default.
+	// Larger inputs are split into runs written to temporary files.
+	MaxMemory ByteSize
+	// TempDir is the directory of the temporary files, os.TempDir() by default.
+	// It needs about as much free space as the input.
+	TempDir string
+	// MergeWidth is the number of runs merged at once, limiting the open files. 64 by default.
+	MergeWidth int
+}
+
+// SortLines sorts the lines of the file src into the file dst, which is written atomically and may be src.
+// Inputs larger than MaxMemory are sorted in runs written to temporary files which are then merged,
+// so that files larger than the memory can be sorted. The sort is stable: lines with equal keys keep
+// their order. Lines are read like LineReader, without \r\n or a leading BOM, and written ending with \n.
+//
+// 外部排序文件的行，支持超过内存大小的文件，可去重
+// 示例:
+//
+//	// 按第二列数值降序排序并去重
+//	err := file.SortLines("scores.tsv", "sorted.tsv", file.SortOptions{
+//		Order:   file.SortNumeric,
+//		Key:     func(line []byte) []byte { _, after, _ := bytes.Cut(line, []byte("\t")); return after },
+//		Reverse: true,
+//		Unique:  true,
+//	})
+func SortLines(src, dst string, opts SortOptions) error {
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = defaultSortMemory
+	}
+	if opts.MergeWidth < 2 {
+		opts.MergeWidth = defaultSortMergeWidth
+	}
+	s := &sorter{opts: opts, cmp: opts.Compare}
+	if s.cmp == nil {
+		switch opts.Order {
+		case SortNumeric:
+			s.cmp = compareNumeric
+		case SortNatural:
+			s.cmp = compareNatural
+		default:
+			s.cmp = bytes.Compare
+		}
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	lr, err := OpenLines(src, LineOptions{})
+	if err != nil {
+		return err
+	}
+	defer lr.Close()
+
+	// 小文件直接在内存中排序，不写临时文件
+	lines, done, err := s.readRun(lr)
+	if err != nil {
+		return err
+	}
+	if done {
+		// Windows上无法替换仍然打开的文件，dst为src时需要先关闭
+		_ = lr.Close()
+		return s.writeDst(dst, info.Mode().Perm(), func(w *bufio.Writer) error {
+			return s.writeSorted(w, lines)
+		})
+	}
+
+	space, err := NewTempSpace(TempOptions{Dir: opts.TempDir, Pattern: "sortlines-*"})
+	if err != nil {
+		return err
+	}
+	defer space.Close()
+	var runs []string
+	for {
+		run, err := s.writeRun(space, lines)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		if done {
+			break
+		}
+		if lines, done, err = s.readRun(lr); err != nil {
+			return err
+		}
+	}
+	_ = lr.Close()
+
+	// 分段合并，直到剩余的段可以一次合并
+	for len(runs) > opts.MergeWidth {
+		var merged []string
+		for i := 0; i < len(runs); i += opts.MergeWidth {
+			group := runs[i:min(i+opts.MergeWidth, len(runs))]
+			run, err := s.mergeRuns(space, group)
+			if err != nil {
+				return err
+			}
+			merged = append(merged, run)
+		}
+		runs = merged
+	}
+	return s.writeDst(dst, info.Mode().Perm(), func(w *bufio.Writer) error {
+		return s.merge(w, runs)
+	})
+}
+
+// sorter holds the state of a SortLines call.
+type sorter struct {
+	opts SortOptions
+	cmp  func(a, b []byte) int
+}
+
+// sortLine is a line with its key.
+type sortLine struct {
+	line, key []byte
+}
+
+func (s *sorter) key(line []byte) []byte {
+	if s.opts.Key == nil {
+		return line
+	}
+	return s.opts.Key(line)
+}
+
+func (s *sorter) compare(a, b []byte) int {
+	if s.opts.Reverse {
+		return s.cmp(b, a)
+	}
+	return s.cmp(a, b)
+}
+
+// readRun reads the lines of the next run, up to MaxMemory, and sorts them.
+// done reports whether the input is exhausted.
+func (s *sorter) readRun(lr *LineReader) (lines []sortLine, done bool, err error) {
+	var size int64
+	for size < int64(s.opts.MaxMemory) {
+		if !lr.Next() {
+			if err := lr.Err(); err != nil {
+				return nil, false, err
+			}
+			done = true
+			break
+		}
+		line := bytes.Clone(lr.Bytes())
+		key := s.key(line)
+		lines = append(lines, sortLine{line: line, key: key})
+		size += int64(len(line)) + sortLineOverhead
+		if s.opts.Key != nil {
+			// 键可能是行的子切片，按最坏情况计算
+			size += int64(len(key))
+		}
+	}
+	slices.SortStableFunc(lines, func(a, b sortLine) int {
+		return s.compare(a.key, b.key)
+	})
+	return lines, done, nil
+}
+
+// writeSorted writes sorted lines, dropping duplicate keys in unique mode.
+func (s *sorter) writeSorted(w *bufio.Writer, lines []sortLine) error {
+	for i, l := range lines {
+		if s.opts.Unique && i > 0 && s.compare(lines[i-1].key, l.key) == 0 {
+			continue
+		}
+		if err := writeLine(w, l.line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLine(w *bufio.Writer, line []byte) error {
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// writeRun writes sorted lines to a new temporary file and returns its path.
+func (s *sorter) writeRun(space *TempSpace, lines []sortLine) (string, error) {
+	return s.writeTemp(space, func(w *bufio.Writer) error {
+		return s.writeSorted(w, lines)
+	})
+}
+
+// mergeRuns merges runs into a new temporary file, removing them, and returns its path.
+func (s *sorter) mergeRuns(space *TempSpace, runs []string) (string, error) {
+	run, err := s.writeTemp(space, func(w *bufio.Writer) error {
+		return s.merge(w, runs)
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range runs {
+		if err := space.Remove(r); err != nil {
+			return "", err
+		}
+	}
+	return run, nil
+}
+
+func (s *sorter) writeTemp(space *TempSpace, fn func(w *bufio.Writer) error) (string, error) {
+	f, err := space.CreateTemp("", "run-*")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriterSize(f, 64*1024)
+	if err := fn(w); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+func (s *sorter) writeDst(dst string, perm os.FileMode, fn func(w *bufio.Writer) error) error {
+	aw, err := NewAtomicWriter(dst, perm, AtomicOptions{})
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(aw, 64*1024)
+	if err := fn(w); err != nil {
+		_ = aw.Abort()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = aw.Abort()
+		return err
+	}
+	return aw.Close()
+}
+
+// merge writes the k-way merge of the sorted runs to w.
+func (s *sorter) merge(w *bufio.Writer, runs []string) error {
+	h := &mergeHeap{s: s}
+	defer func() {
+		for _, c := range h.cursors {
+			_ = c.lr.Close()
+		}
+	}()
+	for i, run := range runs {
+		// 段文件中的行保持原样，不再去除\r和BOM
+		lr, err := OpenLines(run, LineOptions{KeepBOM: true, KeepCR: true})
+		if err != nil {
+			return err
+		}
+		c := &mergeCursor{lr: lr, run: i}
+		if !c.next(s) {
+			_ = lr.Close()
+			if err := lr.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		h.cursors = append(h.cursors, c)
+	}
+	heap.Init(h)
+
+	var last []byte
+	first := true
+	for h.Len() > 0 {
+		c := h.cursors[0]
+		if !s.opts.Unique || first || s.compare(last, c.key) != 0 {
+			if err := writeLine(w, c.line); err != nil {
+				return err
+			}
+			if s.opts.Unique {
+				last = append(last[:0], c.key...)
+				first = false
+			}
+		}
+		if c.next(s) {
+			heap.Fix(h, 0)
+			continue
+		}
+		if err := c.lr.Err(); err != nil {
+			return err
+		}
+		_ = c.lr.Close()
+		heap.Pop(h)
+	}
+	return nil
+}
+
+// mergeCursor is the current line of a run being merged.
+type mergeCursor struct {
+	lr        *LineReader
+	run       int
+	line, key []byte
+}
+
+func (c *mergeCursor) next(s *sorter) bool {
+	if !c.lr.Next() {
+		return false
+	}
+	c.line = c.lr.Bytes()
+	c.key = s.key(c.line)
+	return true
+}
+
+// mergeHeap orders cursors by key, then by run so that the merge is stable.
+type mergeHeap struct {
+	s       *sorter
+	cursors []*mergeCursor
+}
+
+func (h *mergeHeap) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	if c := h.s.compare(a.key, b.key); c != 0 {
+		return c < 0
+	}
+	return a.run < b.run
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap) Push(x any) { h.cursors = append(h.cursors, x.(*mergeCursor)) }
+
+func (h *mergeHeap) Pop() any {
+	c := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return c
+}
+
+// compareNumeric compares the numbers at the start of a and b, see SortNumeric.
+func compareNumeric(a, b []byte) int {
+	x, okA := leadingNumber(a)
+	y, okB := leadingNumber(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// leadingNumber parses the decimal number, with optional sign and fraction, at the start of b
+// after white space.
+func leadingNumber(b []byte) (float64, bool) {
+	b = bytes.TrimLeft(b, " \t")
+	i := 0
+	if i < len(b) && (b[i] == '-' || b[i] == '+') {
+		i++
+	}
+	digits := 0
+	for ; i < len(b) && isDigit(b[i]); i++ {
+		digits++
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		for ; i < len(b) && isDigit(b[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(string(b[:i]), 64)
+	// 超出范围时ParseFloat返回±Inf，仍可比较
+	return n, err == nil || errors.Is(err, strconv.ErrRange)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// compareNatural compares a and b in natural order, see SortNatural.
+func compareNatural(a, b []byte) int {
+	for len(a) > 0 && len(b) > 0 {
+		if !isDigit(a[0]) || !isDigit(b[0]) {
+			if a[0] != b[0] {
+				return int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		// 比较数字串的值：去掉前导0后先比长度再逐字节比较
+		i, j := digitRun(a), digitRun(b)
+		x, y := bytes.TrimLeft(a[:i], "0"), bytes.TrimLeft(b[:j], "0")
+		if len(x) != len(y) {
+			return len(x) - len(y)
+		}
+		if c := bytes.Compare(x, y); c != 0 {
+			return c
+		}
+		a, b = a[i:], b[j:]
+	}
+	return len(a) - len(b)
+}
+
+func digitRun(b []byte) int {
+	i := 0
+	for i < len(b) && isDigit(b[i]) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,157 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSortLines(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(src, []byte("\ufeffb\r\nc\na\nb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts SortOptions
+		want string
+	}{
+		{"lexical", SortOptions{}, "a\nb\nb\nc\n"},
+		{"unique", SortOptions{Unique: true}, "a\nb\nc\n"},
+		{"reverse", SortOptions{Reverse: true, Unique: true}, "c\nb\na\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(dir, tt.name+".txt")
+			if err := SortLines(src, dst, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			assertFileContent(t, dst, tt.want)
+		})
+	}
+
+	// 原地排序
+	if err := SortLines(src, src, SortOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	assertFileContent(t, src, "a\nb\nb\nc\n")
+	if info, _ := os.Stat(src); info.Mode().Perm() != 0o600 {
+		t.Errorf("perm = %v", info.Mode().Perm())
+	}
+}
+
+func TestSortLinesOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  SortOptions
+		input []string
+		want  []string
+	}{
+		{"numeric", SortOptions{Order: SortNumeric}, []string{"10 a", "9 b", "x", " -1.5", "2e", "010"}, []string{"x", " -1.5", "2e", "9 b", "10 a", "010"}},
+		{"numeric unique", SortOptions{Order: SortNumeric, Unique: true}, []string{"10 a", "010", "1"}, []string{"1", "10 a"}},
+		{"natural", SortOptions{Order: SortNatural}, []string{"file10.txt", "file2.txt", "file1.txt", "file02.txt", "File3"}, []string{"File3", "file1.txt", "file2.txt", "file02.txt", "file10.txt"}},
+		{"key", SortOptions{Key: func(line []byte) []byte { _, after, _ := bytes.Cut(line, []byte(",")); return after }}, []string{"1,c", "2,a", "3,b", "4,a"}, []string{"2,a", "4,a", "3,b", "1,c"}},
+		{"compare", SortOptions{Compare: func(a, b []byte) int { return len(a) - len(b) }, Reverse: true}, []string{"bb", "a", "ccc", "dd"}, []string{"ccc", "bb", "dd", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+			if err := WriteLines(src, tt.input, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := SortLines(src, dst, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadLines(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortLinesExternal(t *testing.T) {
+	dir := t.TempDir()
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.Mkdir(tmp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%05d", rng.Intn(3000))
+	}
+	src, dst := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	if err := WriteLines(src, lines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每段约100行，合并宽度为4，需要多轮合并
+	opts := SortOptions{MaxMemory: 100 * (5 + sortLineOverhead), MergeWidth: 4, TempDir: tmp, Unique: true}
+	if err := SortLines(src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadLines(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	var want []string
+	for _, l := range lines {
+		if !seen[l] {
+			seen[l] = true
+			want = append(want, l)
+		}
+	}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %d lines, want %d", len(got), len(want))
+	}
+
+	// 多段排序也可以原地进行
+	if err := SortLines(src, src, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ReadLines(src); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("in place: got %d lines, want %d", len(got), len(want))
+	}
+	if names := listDir(t, tmp); len(names) != 0 {
+		t.Errorf("temporary files left: %v", names)
+	}
+}
+
+func TestSortLinesStable(t *testing.T) {
+	dir := t.TempDir()
+	var lines, want []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, fmt.Sprintf("%d %03d", i%3, i))
+	}
+	for k := 0; k < 3; k++ {
+		for i := k; i < 300; i += 3 {
+			want = append(want, fmt.Sprintf("%d %03d", k, i))
+		}
+	}
+	src, dst := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	if err := WriteLines(src, lines, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := SortOptions{Order: SortNumeric, MaxMemory: 20 * (5 + sortLineOverhead), MergeWidth: 3}
+	if err := SortLines(src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ReadLines(dst)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("lines with equal keys changed order")
+	}
+}
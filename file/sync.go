@@ -0,0 +1,214 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CompareMode decides how Sync detects that a file changed.
+//
+// Sync判断文件是否变化的方式
+type CompareMode int
+
+const (
+	// CompareSizeTime treats files with the same size and modification time as identical.
+	CompareSizeTime CompareMode = iota
+	// CompareContent compares the content hashes of files with the same size.
+	CompareContent
+)
+
+// ChangeKind is the kind of a Change.
+//
+// 变更类型
+type ChangeKind int
+
+const (
+	// ChangeAdded is a file or directory that exists in the source only.
+	ChangeAdded ChangeKind = iota
+	// ChangeModified is a file that differs between source and destination.
+	ChangeModified
+	// ChangeDeleted is a file or directory that exists in the destination only.
+	ChangeDeleted
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeModified:
+		return "modified"
+	case ChangeDeleted:
+		return "deleted"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a difference found by Sync.
+//
+// Sync发现的一个差异
+type Change struct {
+	Kind ChangeKind
+	// Path is the slash separated path relative to the synced directories.
+	Path  string
+	IsDir bool
+}
+
+// SyncOptions configures Sync.
+//
+// Sync的配置项
+type SyncOptions struct {
+	// Compare selects how changed files are detected.
+	Compare CompareMode
+	// ModTimeWindow is the tolerance used when comparing modification times,
+	// e.g. 2s for FAT filesystems.
+	ModTimeWindow time.Duration
+	// Delete removes files and directories from dst that do not exist in src.
+	Delete bool
+	// Exclude ignores the source and destination paths matching one of these globs (see MatchGlob).
+	// Excluded destination files are never deleted.
+	Exclude []string
+	// DryRun only computes the changes without touching dst.
+	DryRun bool
+}
+
+// Sync makes the directory dst a mirror of src, copying only new and changed files.
+// Copied files keep their modification times so that later syncs can compare them.
+// It returns the changes that were applied, or that would be applied with DryRun.
+// Additions and modifications come first, in walk order, followed by deletions.
+//
+// 单向同步目录，只复制新增和变化的文件，可选删除多余文件，DryRun时只返回变更列表
+// 示例:
+//
+//	changes, err := file.Sync(ctx, "dist", "/srv/www", file.SyncOptions{Delete: true, DryRun: true})
+//	for _, c := range changes {
+//		fmt.Println(c.Kind, c.Path)
+//	}
+func Sync(ctx context.Context, src, dst string, opts SyncOptions) ([]Change, error) {
+	walkOpts := WalkOptions{FollowSymlinks: true}
+	if len(opts.Exclude) > 0 {
+		walkOpts.Prune = ByGlob(opts.Exclude...)
+		walkOpts.Filters = []Filter{Not(ByGlob(opts.Exclude...))}
+	}
+
+	srcEntries, err := Find(src, walkOpts)
+	if err != nil {
+		return nil, err
+	}
+	var dstEntries []Entry
+	if ok, err := IsPathExist(dst); err != nil {
+		return nil, err
+	} else if ok {
+		if dstEntries, err = Find(dst, walkOpts); err != nil {
+			return nil, err
+		}
+	}
+	dstByRel := make(map[string]Entry, len(dstEntries))
+	for _, e := range dstEntries {
+		dstByRel[e.Rel] = e
+	}
+
+	var changes, deletions []Change
+	srcRels := make(map[string]struct{}, len(srcEntries))
+	for _, s := range srcEntries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		srcRels[s.Rel] = struct{}{}
+		d, exists := dstByRel[s.Rel]
+		switch {
+		case !exists:
+			changes = append(changes, Change{Kind: ChangeAdded, Path: s.Rel, IsDir: s.IsDir()})
+		case s.IsDir() != d.IsDir():
+			// 类型不同，先删除再添加
+			deletions = append(deletions, Change{Kind: ChangeDeleted, Path: d.Rel, IsDir: d.IsDir()})
+			changes = append(changes, Change{Kind: ChangeAdded, Path: s.Rel, IsDir: s.IsDir()})
+		case !s.IsDir():
+			same, err := sameFile(s, d, opts)
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				changes = append(changes, Change{Kind: ChangeModified, Path: s.Rel})
+			}
+		}
+	}
+	if opts.Delete {
+		for _, d := range dstEntries {
+			if _, ok := srcRels[d.Rel]; !ok {
+				deletions = append(deletions, Change{Kind: ChangeDeleted, Path: d.Rel, IsDir: d.IsDir()})
+			}
+		}
+	}
+
+	// 类型冲突的删除需要在复制之前执行
+	var conflicts []Change
+	for _, c := range deletions {
+		if _, ok := srcRels[c.Path]; ok {
+			conflicts = append(conflicts, c)
+		}
+	}
+	changes = append(changes, deletions...)
+	if opts.DryRun {
+		return changes, nil
+	}
+
+	for _, c := range conflicts {
+		if err := os.RemoveAll(filepath.Join(dst, filepath.FromSlash(c.Path))); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return nil, err
+	}
+	cp := newCopier(ctx, CopyOptions{PreserveTimes: true})
+	for _, c := range changes {
+		target := filepath.Join(dst, filepath.FromSlash(c.Path))
+		switch {
+		case c.Kind == ChangeDeleted:
+		case c.IsDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := cp.copyEntry(filepath.Join(src, filepath.FromSlash(c.Path)), target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 逆序删除，先删子项再删目录
+	for i := len(deletions) - 1; i >= 0; i-- {
+		if _, ok := srcRels[deletions[i].Path]; ok {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dst, filepath.FromSlash(deletions[i].Path))); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func sameFile(src, dst Entry, opts SyncOptions) (bool, error) {
+	if src.Size() != dst.Size() {
+		return false, nil
+	}
+	if opts.Compare == CompareContent {
+		srcSum, err := Hash(src.Path, SHA256)
+		if err != nil {
+			return false, err
+		}
+		dstSum, err := Hash(dst.Path, SHA256)
+		if err != nil {
+			return false, err
+		}
+		return srcSum == dstSum, nil
+	}
+	diff := src.ModTime().Sub(dst.ModTime())
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= opts.ModTimeWindow, nil
+}
@@ -0,0 +1,101 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	root := makeTree(t, map[string]string{
+		"src/same.txt":     "same",
+		"src/changed.txt":  "new content",
+		"src/added.txt":    "added",
+		"src/dir/deep.txt": "deep",
+		"src/cache/x.tmp":  "ignored",
+		"dst/same.txt":     "same",
+		"dst/changed.txt":  "old content",
+		"dst/extra/e.txt":  "extra",
+		"dst/keep.tmp":     "excluded",
+	})
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, p := range []string{"src/same.txt", "dst/same.txt"} {
+		if err := os.Chtimes(filepath.Join(root, p), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := SyncOptions{Delete: true, Exclude: []string{"*.tmp", "cache"}, DryRun: true}
+	want := []Change{
+		{Kind: ChangeAdded, Path: "added.txt"},
+		{Kind: ChangeModified, Path: "changed.txt"},
+		{Kind: ChangeAdded, Path: "dir", IsDir: true},
+		{Kind: ChangeAdded, Path: "dir/deep.txt"},
+		{Kind: ChangeDeleted, Path: "extra", IsDir: true},
+		{Kind: ChangeDeleted, Path: "extra/e.txt"},
+	}
+	changes, err := Sync(ctx, src, dst, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Sync() dry run = %v, want %v", changes, want)
+	}
+	if ok, _ := IsPathExist(filepath.Join(dst, "added.txt")); ok {
+		t.Error("Sync() dry run modified dst")
+	}
+
+	opts.DryRun = false
+	if changes, err = Sync(ctx, src, dst, opts); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Sync() = %v, want %v", changes, want)
+	}
+	got, _ := Find(dst, WalkOptions{})
+	wantTree := []string{"added.txt", "changed.txt", "dir", "dir/deep.txt", "keep.tmp", "same.txt"}
+	if !reflect.DeepEqual(rels(got), wantTree) {
+		t.Errorf("Sync() tree = %v, want %v", rels(got), wantTree)
+	}
+	assertFileContent(t, filepath.Join(dst, "changed.txt"), "new content")
+
+	// 第二次同步没有变更
+	if changes, err = Sync(ctx, src, dst, opts); err != nil || len(changes) != 0 {
+		t.Errorf("Sync() again = %v, %v, want no changes", changes, err)
+	}
+}
+
+func TestSyncCompareContent(t *testing.T) {
+	ctx := context.Background()
+	root := makeTree(t, map[string]string{
+		"src/a.txt": "aaaa",
+		"src/b.txt": "bbbb",
+		"dst/a.txt": "aaaa",
+		"dst/b.txt": "BBBB",
+		"src/c/":    "",
+		"dst/c":     "file in place of a dir",
+	})
+	src, dst := filepath.Join(root, "src"), filepath.Join(root, "dst")
+
+	changes, err := Sync(ctx, src, dst, SyncOptions{Compare: CompareContent})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	want := []Change{
+		{Kind: ChangeModified, Path: "b.txt"},
+		{Kind: ChangeAdded, Path: "c", IsDir: true},
+		{Kind: ChangeDeleted, Path: "c"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Sync() = %v, want %v", changes, want)
+	}
+	assertFileContent(t, filepath.Join(dst, "b.txt"), "bbbb")
+	if ok, _ := IsDir(filepath.Join(dst, "c")); !ok {
+		t.Error("Sync() should replace the file c with a directory")
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !unix && !windows
+
+package file
+
+import "os"
+
+// copyOwner does nothing on this platform.
+func copyOwner(path string, info os.FileInfo) {}
+
+// syncDir fsyncs a directory so that a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// 部分平台不支持同步目录，忽略错误
+	_ = d.Sync()
+	return nil
+}
+
+// isCrossDevice always reports false on this platform, so renames are never retried as copies.
+func isCrossDevice(err error) bool {
+	return false
+}
+
+// processAlive cannot check processes on this platform and reports every process as running.
+func processAlive(pid int) bool {
+	return true
+}
+
+// fileID is not available on this platform, hard links are counted like separate files.
+func fileID(info os.FileInfo) (dev, ino uint64, linked bool) {
+	return 0, 0, false
+}
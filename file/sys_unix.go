@@ -0,0 +1,48 @@
+//go:build unix
+
+package file
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// copyOwner gives path the owner and group of info, ignoring failures
+// (only root may give files away).
+func copyOwner(path string, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Chown(path, int(st.Uid), int(st.Gid))
+	}
+}
+
+// syncDir fsyncs a directory so that a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// isCrossDevice reports whether err is a rename failure across filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM表示进程存在但属于其他用户
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// fileID returns the device and inode of info and whether other hard links to it exist.
+func fileID(info os.FileInfo) (dev, ino uint64, linked bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), st.Nlink > 1
+}
@@ -0,0 +1,113 @@
+//go:build windows
+
+package file
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// copyOwner is a no-op on Windows, files inherit the ACL of their directory.
+func copyOwner(path string, info os.FileInfo) {}
+
+// syncDir is a no-op on Windows, directories cannot be fsynced.
+func syncDir(dir string) error {
+	return nil
+}
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when renaming across volumes.
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDevice reports whether err is a rename failure across volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, errorNotSameDevice)
+}
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	// errorLockViolation is ERROR_LOCK_VIOLATION, returned when a lock is held with LOCKFILE_FAIL_IMMEDIATELY.
+	errorLockViolation = syscall.Errno(33)
+	// stillActive is the exit code of a process that has not exited.
+	stillActive = 259
+)
+
+// lockFile locks the first byte of f with LockFileEx, which unlike flock is mandatory on Windows:
+// other processes cannot write that byte while it is locked. The lock files are never written, so
+// this does not matter. Without block it fails with errLockHeld if the lock is taken.
+func lockFile(f *os.File, shared, block bool) error {
+	var flags uintptr
+	if !shared {
+		flags |= lockfileExclusiveLock
+	}
+	if !block {
+		flags |= lockfileFailImmediately
+	}
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r != 0 {
+		return nil
+	}
+	return err
+}
+
+// processAlive reports whether a process with the given pid is running.
+func processAlive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// 拒绝访问说明进程存在
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// fileID is not available from a FileInfo on Windows, hard links are counted like separate files.
+func fileID(info os.FileInfo) (dev, ino uint64, linked bool) {
+	return 0, 0, false
+}
+
+var procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// diskUsage reports the space of the volume holding path.
+func diskUsage(path string) (DiskUsage, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	var available, total, free uint64
+	r, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&available)), uintptr(unsafe.Pointer(&total)), uintptr(unsafe.Pointer(&free)))
+	if r == 0 {
+		return DiskUsage{}, err
+	}
+	return DiskUsage{
+		Total:     ByteSize(total),
+		Free:      ByteSize(free),
+		Available: ByteSize(available),
+	}, nil
+}
@@ -0,0 +1,375 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTempSpaceFull is returned when a write would make a TempSpace exceed its MaxSize.
+var ErrTempSpaceFull = errors.New("temp space size limit exceeded")
+
+// TempOptions configures a TempSpace.
+//
+// 临时空间的配置项
+type TempOptions struct {
+	// Dir is the directory in which the root of the space is created, os.TempDir() by default.
+	Dir string
+	// Pattern is the name of the root directory, with the last "*" replaced by a random string
+	// like os.MkdirTemp. "tempspace-*" by default.
+	Pattern string
+	// MaxSize limits the total size of the files written through the space and moved into it with Adopt.
+	// 0 is unlimited.
+	MaxSize ByteSize
+}
+
+// TempSpace manages temporary files and directories below a private root directory,
+// and removes everything below it on Close, including files other code moved in.
+// It is safe for concurrent use.
+//
+// 管理临时文件和目录，Close时删除全部内容
+// 示例:
+//
+//	space, err := file.NewTempSpace(file.TempOptions{MaxSize: file.GiB})
+//	defer space.Close()
+//	f, err := space.CreateTemp("", "upload-*.bin")
+type TempSpace struct {
+	root string
+	opts TempOptions
+
+	mu     sync.Mutex
+	used   int64
+	files  map[*TempFile]bool
+	closed bool
+}
+
+// NewTempSpace creates the root directory of a new TempSpace.
+//
+// 创建临时空间
+func NewTempSpace(opts TempOptions) (*TempSpace, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = "tempspace-*"
+	}
+	root, err := os.MkdirTemp(opts.Dir, opts.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &TempSpace{root: root, opts: opts, files: map[*TempFile]bool{}}, nil
+}
+
+// TestingTB is the part of testing.TB used by NewTestTempSpace, declared here so that the package
+// does not import testing. *testing.T, *testing.B and *testing.F implement it.
+//
+// NewTestTempSpace需要的testing.TB方法
+type TestingTB interface {
+	Helper()
+	TempDir() string
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// NewTestTempSpace creates a TempSpace in the temporary directory of the test and closes it when
+// the test and its subtests complete. It fails the test if the space cannot be created or removed.
+//
+// 创建测试用的临时空间，测试结束时自动清理
+// 示例:
+//
+//	func TestImport(t *testing.T) {
+//		space := file.NewTestTempSpace(t, file.TempOptions{})
+//		...
+//	}
+func NewTestTempSpace(tb TestingTB, opts TempOptions) *TempSpace {
+	tb.Helper()
+	if opts.Dir == "" {
+		opts.Dir = tb.TempDir()
+	}
+	s, err := NewTempSpace(opts)
+	if err != nil {
+		tb.Fatalf("NewTempSpace() error = %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			tb.Errorf("TempSpace.Close() error = %v", err)
+		}
+	})
+	return s
+}
+
+// Root returns the root directory of the space.
+func (s *TempSpace) Root() string {
+	return s.root
+}
+
+// Join returns the path of name inside the space, e.g. to rename a file into it. It fails with
+// ErrPathEscapes if name would resolve outside of the space. Files put there without Adopt are
+// removed on Close but do not count towards MaxSize.
+//
+// 返回空间内的路径
+func (s *TempSpace) Join(name string) (string, error) {
+	if err := s.check(); err != nil {
+		return "", err
+	}
+	return SecureJoin(s.root, name)
+}
+
+// CreateTemp creates a new temporary file in the directory dir of the space, the root if dir is empty,
+// like os.CreateTemp. The writes to the returned file count towards MaxSize.
+//
+// 在空间内创建临时文件
+func (s *TempSpace) CreateTemp(dir, pattern string) (*TempFile, error) {
+	p, err := s.Join(dir)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(p, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		// 并发的Close已删除或正在删除根目录
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, os.ErrClosed
+	}
+	t := &TempFile{f: f, space: s}
+	s.files[t] = true
+	return t, nil
+}
+
+// MkdirTemp creates a new temporary directory in the directory dir of the space, the root if dir is empty,
+// like os.MkdirTemp.
+//
+// 在空间内创建临时目录
+func (s *TempSpace) MkdirTemp(dir, pattern string) (string, error) {
+	p, err := s.Join(dir)
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(p, pattern)
+}
+
+// Adopt moves the file or directory at path into the root of the space, keeping its name, so that it
+// is removed on Close, and returns its new path. Its size counts towards MaxSize.
+//
+// 将已有的文件或目录移入空间，由空间负责清理
+func (s *TempSpace) Adopt(path string) (string, error) {
+	dst, err := s.Join(filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	size, err := DirSize(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.reserve(int64(size)); err != nil {
+		return "", err
+	}
+	if err := Move(context.Background(), path, dst, CopyOptions{Overwrite: OverwriteError}); err != nil {
+		s.release(int64(size))
+		return "", err
+	}
+	return dst, nil
+}
+
+// Remove removes the file or directory at path inside the space early, releasing its size.
+//
+// 提前删除空间内的文件或目录
+func (s *TempSpace) Remove(path string) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if !isWithin(s.root, path) || isWithin(path, s.root) {
+		return &os.PathError{Op: "remove", Path: path, Err: ErrPathEscapes}
+	}
+	size, err := DirSize(path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for f := range s.files {
+		if isWithin(path, f.Name()) {
+			_ = f.f.Close()
+			delete(s.files, f)
+		}
+	}
+	s.mu.Unlock()
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	s.release(int64(size))
+	return nil
+}
+
+// Used returns the size counted towards MaxSize.
+func (s *TempSpace) Used() ByteSize {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ByteSize(s.used)
+}
+
+// Close closes the files still open and removes the root directory with everything below it.
+// Calling Close again does nothing.
+//
+// 关闭并删除全部临时文件
+func (s *TempSpace) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	// Windows上无法删除打开的文件
+	for f := range s.files {
+		_ = f.f.Close()
+	}
+	s.files = nil
+	s.mu.Unlock()
+	return os.RemoveAll(s.root)
+}
+
+func (s *TempSpace) check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	return nil
+}
+
+// reserve counts n more bytes, failing with ErrTempSpaceFull if that exceeds MaxSize.
+func (s *TempSpace) reserve(n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	if s.opts.MaxSize > 0 && s.used+n > int64(s.opts.MaxSize) {
+		return fmt.Errorf("%w: %v used of %v", ErrTempSpaceFull, ByteSize(s.used), s.opts.MaxSize)
+	}
+	s.used += n
+	return nil
+}
+
+func (s *TempSpace) release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used = max(s.used-n, 0)
+}
+
+// TempFile is a file created by a TempSpace. Writes that grow it are counted against the MaxSize
+// of the space and fail with ErrTempSpaceFull, writing nothing, when the space is full.
+// Like an *os.File, it is not safe for concurrent use.
+//
+// 临时空间中的文件
+type TempFile struct {
+	f     *os.File
+	space *TempSpace
+	off   int64
+	size  int64
+}
+
+// Name returns the path of the file.
+func (t *TempFile) Name() string {
+	return t.f.Name()
+}
+
+// File returns the underlying file, whose writes are not counted against MaxSize.
+func (t *TempFile) File() *os.File {
+	return t.f
+}
+
+func (t *TempFile) Read(p []byte) (int, error) {
+	n, err := t.f.Read(p)
+	t.off += int64(n)
+	return n, err
+}
+
+func (t *TempFile) ReadAt(p []byte, off int64) (int, error) {
+	return t.f.ReadAt(p, off)
+}
+
+func (t *TempFile) Write(p []byte) (int, error) {
+	n, err := t.WriteAt(p, t.off)
+	t.off += int64(n)
+	return n, err
+}
+
+// WriteAt writes p at offset off.
+func (t *TempFile) WriteAt(p []byte, off int64) (int, error) {
+	old := t.size
+	grow := max(off+int64(len(p))-old, 0)
+	if err := t.space.reserve(grow); err != nil {
+		return 0, &os.PathError{Op: "write", Path: t.Name(), Err: err}
+	}
+	n, err := t.f.WriteAt(p, off)
+	// 短写时归还多预留的部分
+	grown := max(off+int64(n)-old, 0)
+	t.size = old + grown
+	t.space.release(grow - grown)
+	return n, err
+}
+
+// WriteString writes s.
+func (t *TempFile) WriteString(s string) (int, error) {
+	return t.Write([]byte(s))
+}
+
+func (t *TempFile) Seek(offset int64, whence int) (int64, error) {
+	off, err := t.f.Seek(offset, whence)
+	if err == nil {
+		t.off = off
+	}
+	return off, err
+}
+
+// Truncate changes the size of the file, releasing or reserving the difference.
+func (t *TempFile) Truncate(size int64) error {
+	if size > t.size {
+		if err := t.space.reserve(size - t.size); err != nil {
+			return &os.PathError{Op: "truncate", Path: t.Name(), Err: err}
+		}
+	}
+	if err := t.f.Truncate(size); err != nil {
+		if size > t.size {
+			t.space.release(size - t.size)
+		}
+		return err
+	}
+	if size < t.size {
+		t.space.release(t.size - size)
+	}
+	t.size = size
+	return nil
+}
+
+func (t *TempFile) Stat() (fs.FileInfo, error) {
+	return t.f.Stat()
+}
+
+func (t *TempFile) Sync() error {
+	return t.f.Sync()
+}
+
+// Close closes the file. It stays in the space, and counted, until removed.
+func (t *TempFile) Close() error {
+	t.space.mu.Lock()
+	delete(t.space.files, t)
+	t.space.mu.Unlock()
+	return t.f.Close()
+}
+
+var _ interface {
+	io.ReadWriteSeeker
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+} = (*TempFile)(nil)
@@ -0,0 +1,153 @@
+package file
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTempSpace(t *testing.T) {
+	s, err := NewTempSpace(TempOptions{Dir: t.TempDir(), Pattern: "job-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(filepath.Base(s.Root()), "job-") {
+		t.Errorf("Root() = %q", s.Root())
+	}
+
+	f, err := s.CreateTemp("", "a-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(f); string(data) != "hello" {
+		t.Errorf("read back %q", data)
+	}
+
+	dir, err := s.MkdirTemp("", "work-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTemp(filepath.Base(dir), "b-*"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他代码移入的文件也会被删除
+	outside := filepath.Join(t.TempDir(), "result.bin")
+	if err := os.WriteFile(outside, []byte("result"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := s.Join("renamed.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(outside, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Join("../escape"); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("Join(../escape) error = %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.Root()); !os.IsNotExist(err) {
+		t.Errorf("root still exists after Close: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if _, err := s.CreateTemp("", "x"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("CreateTemp() after Close error = %v", err)
+	}
+}
+
+func TestTempSpaceMaxSize(t *testing.T) {
+	s := NewTestTempSpace(t, TempOptions{MaxSize: 10})
+
+	f, err := s.CreateTemp("", "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("123456")); err != nil {
+		t.Fatal(err)
+	}
+	// 覆盖已有内容不增加大小
+	if _, err := f.WriteAt([]byte("abc"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if s.Used() != 6 {
+		t.Errorf("Used() = %d, want 6", s.Used())
+	}
+	if _, err := f.Write([]byte("78901")); !errors.Is(err, ErrTempSpaceFull) {
+		t.Errorf("Write() over MaxSize error = %v", err)
+	}
+	if err := f.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("xyz")); err != nil {
+		t.Errorf("Write() after Truncate error = %v", err)
+	}
+	if s.Used() != 9 {
+		t.Errorf("Used() = %d, want 9", s.Used())
+	}
+
+	outside := filepath.Join(t.TempDir(), "big")
+	if err := os.WriteFile(outside, make([]byte, 5), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Adopt(outside); !errors.Is(err, ErrTempSpaceFull) {
+		t.Errorf("Adopt() over MaxSize error = %v", err)
+	}
+	if err := s.Remove(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if s.Used() != 0 {
+		t.Errorf("Used() after Remove = %d", s.Used())
+	}
+	adopted, err := s.Adopt(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(adopted) != s.Root() || s.Used() != 5 {
+		t.Errorf("Adopt() = %q, Used() = %d", adopted, s.Used())
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("Adopt() left the source: %v", err)
+	}
+
+	if err := s.Remove(filepath.Dir(s.Root())); !errors.Is(err, ErrPathEscapes) {
+		t.Errorf("Remove(outside) error = %v", err)
+	}
+}
+
+func TestNewTestTempSpace(t *testing.T) {
+	var root string
+	t.Run("sub", func(t *testing.T) {
+		s := NewTestTempSpace(t, TempOptions{})
+		root = s.Root()
+		f, err := s.CreateTemp("", "open-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 未关闭的文件由Cleanup关闭
+		f.WriteString("x")
+	})
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("root still exists after the test: %v", err)
+	}
+}
+
+var (
+	_ TestingTB = (*testing.T)(nil)
+	_ TestingTB = (*testing.B)(nil)
+	_ TestingTB = (*testing.F)(nil)
+)
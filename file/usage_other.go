@@ -0,0 +1,10 @@
+//go:build !windows && !linux && !darwin && !freebsd
+
+package file
+
+import "errors"
+
+// diskUsage is not implemented on this platform.
+func diskUsage(path string) (DiskUsage, error) {
+	return DiskUsage{}, errors.ErrUnsupported
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package file
+
+import "syscall"
+
+// diskUsage reports the space of the filesystem holding path, using statfs.
+func diskUsage(path string) (DiskUsage, error) {
+	var st syscall.Statfs_t
+	for {
+		err := syscall.Statfs(path, &st)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return DiskUsage{}, err
+		}
+		break
+	}
+	bsize := uint64(st.Bsize)
+	return DiskUsage{
+		Total:     ByteSize(uint64(st.Blocks) * bsize),
+		Free:      ByteSize(uint64(st.Bfree) * bsize),
+		Available: ByteSize(uint64(st.Bavail) * bsize),
+	}, nil
+}
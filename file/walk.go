@@ -0,0 +1,388 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry is a file or directory found by Walk, Find or a Walker.
+// The embedded FileInfo describes the entry itself, or the symlink target when symlinks are followed.
+//
+// 遍历得到的文件信息
+type Entry struct {
+	fs.FileInfo
+	// Path is the path of the entry, joined onto the walk root.
+	Path string
+	// Rel is the slash separated path relative to the walk root.
+	Rel string
+	// Depth is 1 for the direct children of the root.
+	Depth int
+	// Symlink reports whether the entry is a symbolic link.
+	Symlink bool
+}
+
+// Filter selects entries reported by Walk, Find and Walker.
+//
+// 遍历过滤器
+type Filter func(e Entry) bool
+
+// WalkOptions configures Walk, Find and Walker.
+//
+// 遍历配置项
+type WalkOptions struct {
+	// MaxDepth limits how deep the walk goes, 1 meaning the direct children of the root. 0 is unlimited.
+	MaxDepth int
+	// FollowSymlinks descends into symlinked directories and reports symlinks with the info of
+	// their target. Each directory is visited at most once, so symlink cycles are harmless.
+	FollowSymlinks bool
+	// SkipHidden skips hidden files and does not descend into hidden directories.
+	SkipHidden bool
+	// Filters must all accept an entry for it to be reported. They do not stop the walk from
+	// descending into directories, use Prune for that.
+	Filters []Filter
+	// Prune, if set, stops the walk from descending into the directories it accepts.
+	Prune Filter
+	// OnError is called when a directory cannot be read. Returning nil skips it,
+	// returning an error stops the walk. By default the walk stops on the first error.
+	OnError func(path string, err error) error
+}
+
+// Walker iterates lazily over a directory tree in lexical, depth-first order.
+//
+// 惰性遍历器
+// 示例:
+//
+//	w := file.NewWalker("src", file.WalkOptions{Filters: []file.Filter{file.ByExt(".go")}})
+//	for w.Next() {
+//		fmt.Println(w.Entry().Path)
+//	}
+//	if err := w.Err(); err != nil { ... }
+type Walker struct {
+	fsys    fs.FS
+	opts    WalkOptions
+	stack   []Entry
+	cur     Entry
+	hasCur  bool
+	skip    bool
+	visited map[string]bool
+	err     error
+}
+
+// NewWalker returns a Walker over root. If root is a directory only its contents are reported;
+// if it is a file, the walker reports that single file.
+//
+// 创建遍历器
+func NewWalker(root string, opts WalkOptions) *Walker {
+	return NewWalkerFS(hostFS, root, opts)
+}
+
+// NewWalkerFS returns a Walker over root in fsys. Entry paths are names of fsys.
+// FollowSymlinks only descends into symlinked directories of filesystems that can detect cycles,
+// i.e. DirFS and its Subs; elsewhere symlinks to directories are reported but not descended into.
+//
+// 创建文件系统上的遍历器
+func NewWalkerFS(fsys fs.FS, root string, opts WalkOptions) *Walker {
+	w := &Walker{fsys: fsys, opts: opts, visited: map[string]bool{}}
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		w.err = err
+		return w
+	}
+	if info.IsDir() {
+		w.cur = Entry{FileInfo: info, Path: root}
+		w.hasCur = true
+		return w
+	}
+	w.stack = append(w.stack, Entry{FileInfo: info, Path: root, Rel: info.Name(), Depth: 1})
+	return w
+}
+
+// Next advances to the next matching entry. It returns false at the end of the walk or on error.
+//
+// 前进到下一个匹配的文件，结束或出错时返回false
+func (w *Walker) Next() bool {
+	if w.err != nil {
+		return false
+	}
+	for {
+		if w.hasCur && !w.skip && w.cur.IsDir() {
+			if err := w.expand(w.cur); err != nil {
+				w.err = err
+				return false
+			}
+		}
+		w.hasCur, w.skip = false, false
+
+		if len(w.stack) == 0 {
+			return false
+		}
+		e := w.stack[len(w.stack)-1]
+		w.stack = w.stack[:len(w.stack)-1]
+		if w.opts.SkipHidden && IsHidden(e.Name()) {
+			continue
+		}
+
+		w.cur, w.hasCur = e, true
+		if w.match(e) {
+			return true
+		}
+	}
+}
+
+// Entry returns the current entry.
+func (w *Walker) Entry() Entry {
+	return w.cur
+}
+
+// Err returns the error that stopped the walk, if any.
+func (w *Walker) Err() error {
+	return w.err
+}
+
+// SkipDir stops the walker from descending into the current entry, if it is a directory.
+//
+// 不进入当前目录
+func (w *Walker) SkipDir() {
+	w.skip = true
+}
+
+func (w *Walker) match(e Entry) bool {
+	for _, f := range w.opts.Filters {
+		if !f(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Walker) expand(dir Entry) error {
+	if w.opts.MaxDepth > 0 && dir.Depth >= w.opts.MaxDepth {
+		return nil
+	}
+	if dir.Depth > 0 && w.opts.Prune != nil && w.opts.Prune(dir) {
+		return nil
+	}
+	if w.opts.FollowSymlinks {
+		r, ok := w.fsys.(interface {
+			realPath(name string) (string, error)
+		})
+		if !ok && dir.Symlink {
+			return nil
+		}
+		if ok {
+			if real, err := r.realPath(dir.Path); err == nil {
+				if w.visited[real] {
+					return nil
+				}
+				w.visited[real] = true
+			}
+		}
+	}
+
+	entries, err := fs.ReadDir(w.fsys, dir.Path)
+	if err != nil {
+		return w.handleError(dir.Path, err)
+	}
+	children := make([]Entry, 0, len(entries))
+	for _, d := range entries {
+		p := joinPath(w.fsys, dir.Path, d.Name())
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err := w.handleError(p, err); err != nil {
+				return err
+			}
+			continue
+		}
+		child := Entry{
+			FileInfo: info,
+			Path:     p,
+			Rel:      joinRel(dir.Rel, d.Name()),
+			Depth:    dir.Depth + 1,
+			Symlink:  info.Mode()&fs.ModeSymlink != 0,
+		}
+		if child.Symlink && w.opts.FollowSymlinks {
+			// 断开的链接按链接本身报告
+			if target, err := fs.Stat(w.fsys, p); err == nil {
+				child.FileInfo = target
+			}
+		}
+		children = append(children, child)
+	}
+	for i := len(children) - 1; i >= 0; i-- {
+		w.stack = append(w.stack, children[i])
+	}
+	return nil
+}
+
+func (w *Walker) handleError(path string, err error) error {
+	if w.opts.OnError != nil {
+		return w.opts.OnError(path, err)
+	}
+	return err
+}
+
+// Walk calls fn for every matching entry under root. fn may return fs.SkipDir to skip the
+// current directory, or fs.SkipAll to stop the walk without error.
+//
+// 遍历目录，对每个匹配的文件调用fn
+func Walk(root string, opts WalkOptions, fn func(e Entry) error) error {
+	return WalkFS(hostFS, root, opts, fn)
+}
+
+// WalkFS is Walk over root in fsys.
+//
+// 遍历文件系统中的目录
+func WalkFS(fsys fs.FS, root string, opts WalkOptions, fn func(e Entry) error) error {
+	w := NewWalkerFS(fsys, root, opts)
+	for w.Next() {
+		if err := fn(w.Entry()); err != nil {
+			switch {
+			case errors.Is(err, fs.SkipDir):
+				w.SkipDir()
+			case errors.Is(err, fs.SkipAll):
+				return nil
+			default:
+				return err
+			}
+		}
+	}
+	return w.Err()
+}
+
+// Find returns every matching entry under root.
+//
+// 查找所有匹配的文件
+// 示例:
+//
+//	entries, err := file.Find(".", file.WalkOptions{
+//		Filters: []file.Filter{file.FilesOnly(), file.ByGlob("**/*_test.go")},
+//	})
+//	byDir := goutils.GroupBy(entries, func(e file.Entry) string { return filepath.Dir(e.Path) })
+func Find(root string, opts WalkOptions) ([]Entry, error) {
+	return FindFS(hostFS, root, opts)
+}
+
+// FindFS is Find over root in fsys.
+//
+// 查找文件系统中所有匹配的文件
+func FindFS(fsys fs.FS, root string, opts WalkOptions) ([]Entry, error) {
+	var result []Entry
+	err := WalkFS(fsys, root, opts, func(e Entry) error {
+		result = append(result, e)
+		return nil
+	})
+	return result, err
+}
+
+// IsHidden reports whether a file name is hidden by the unix convention of a leading dot.
+//
+// 判断文件名是否以.开头
+func IsHidden(name string) bool {
+	return len(name) > 1 && strings.HasPrefix(name, ".") && name != ".."
+}
+
+// FilesOnly accepts regular files and other non-directories.
+func FilesOnly() Filter {
+	return func(e Entry) bool { return !e.IsDir() }
+}
+
+// DirsOnly accepts directories.
+func DirsOnly() Filter {
+	return func(e Entry) bool { return e.IsDir() }
+}
+
+// ByExt accepts entries whose extension is one of exts, compared case-insensitively.
+// Extensions may be given with or without the leading dot.
+//
+// 按扩展名过滤
+func ByExt(exts ...string) Filter {
+	set := make(map[string]struct{}, len(exts))
+	for _, ext := range exts {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		set[strings.ToLower(ext)] = struct{}{}
+	}
+	return func(e Entry) bool {
+		_, ok := set[strings.ToLower(filepath.Ext(e.Name()))]
+		return ok
+	}
+}
+
+// ByGlob accepts entries whose relative path matches one of patterns. See MatchGlob.
+// Invalid patterns match nothing.
+//
+// 按glob模式过滤
+func ByGlob(patterns ...string) Filter {
+	return func(e Entry) bool {
+		ok, _ := MatchAnyGlob(patterns, e.Rel)
+		return ok
+	}
+}
+
+// BySize accepts entries with min <= size <= max. A negative max means no upper bound.
+//
+// 按文件大小过滤
+func BySize(min, max int64) Filter {
+	return func(e Entry) bool {
+		size := e.Size()
+		return size >= min && (max < 0 || size <= max)
+	}
+}
+
+// ByModTime accepts entries modified within [from, to]. A zero time means no bound.
+//
+// 按修改时间过滤
+func ByModTime(from, to time.Time) Filter {
+	return func(e Entry) bool {
+		t := e.ModTime()
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+	}
+}
+
+// NotHidden rejects hidden entries. Unlike WalkOptions.SkipHidden it still descends into hidden directories.
+func NotHidden() Filter {
+	return func(e Entry) bool { return !IsHidden(e.Name()) }
+}
+
+// And accepts entries accepted by all filters.
+func And(filters ...Filter) Filter {
+	return func(e Entry) bool {
+		for _, f := range filters {
+			if !f(e) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or accepts entries accepted by at least one filter.
+func Or(filters ...Filter) Filter {
+	return func(e Entry) bool {
+		for _, f := range filters {
+			if f(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a filter.
+func Not(f Filter) Filter {
+	return func(e Entry) bool { return !f(e) }
+}
+
+func joinRel(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,159 @@
+package file
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// makeTree creates files (with content) and directories (names ending in /) under a temp dir.
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if name[len(name)-1] == '/' {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func rels(entries []Entry) []string {
+	result := make([]string, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e.Rel)
+	}
+	return result
+}
+
+func TestFind(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.go":            "package a",
+		"b.txt":           "hello world",
+		"sub/c.go":        "package sub",
+		"sub/deep/d.GO":   "package deep",
+		".git/config":     "x",
+		".hidden.go":      "",
+		"empty/":          "",
+		"node_modules/x/": "",
+	})
+
+	tests := []struct {
+		name string
+		opts WalkOptions
+		want []string
+	}{
+		{"all", WalkOptions{}, []string{
+			".git", ".git/config", ".hidden.go", "a.go", "b.txt", "empty",
+			"node_modules", "node_modules/x", "sub", "sub/c.go", "sub/deep", "sub/deep/d.GO",
+		}},
+		{"ext", WalkOptions{Filters: []Filter{ByExt("go")}}, []string{".hidden.go", "a.go", "sub/c.go", "sub/deep/d.GO"}},
+		{"skip hidden", WalkOptions{SkipHidden: true, Filters: []Filter{FilesOnly()}}, []string{"a.go", "b.txt", "sub/c.go", "sub/deep/d.GO"}},
+		{"not hidden", WalkOptions{Filters: []Filter{FilesOnly(), NotHidden()}}, []string{".git/config", "a.go", "b.txt", "sub/c.go", "sub/deep/d.GO"}},
+		{"max depth", WalkOptions{MaxDepth: 1, Filters: []Filter{FilesOnly()}}, []string{".hidden.go", "a.go", "b.txt"}},
+		{"glob", WalkOptions{Filters: []Filter{ByGlob("sub/**/*.go", "*.txt")}}, []string{"b.txt", "sub/c.go"}},
+		{"dirs", WalkOptions{SkipHidden: true, Filters: []Filter{DirsOnly()}, Prune: ByGlob("node_modules")}, []string{"empty", "node_modules", "sub", "sub/deep"}},
+		{"size", WalkOptions{Filters: []Filter{FilesOnly(), BySize(5, -1)}}, []string{"a.go", "b.txt", "sub/c.go", "sub/deep/d.GO"}},
+		{"or not", WalkOptions{Filters: []Filter{FilesOnly(), Not(Or(ByExt(".go"), ByGlob(".git/**")))}}, []string{"b.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(root, tt.opts)
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if !reflect.DeepEqual(rels(got), tt.want) {
+				t.Errorf("Find() = %v, want %v", rels(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFindModTime(t *testing.T) {
+	root := makeTree(t, map[string]string{"old.txt": "", "new.txt": ""})
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "old.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Find(root, WalkOptions{Filters: []Filter{ByModTime(time.Now().Add(-time.Hour), time.Time{})}})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if !reflect.DeepEqual(rels(got), []string{"new.txt"}) {
+		t.Errorf("Find() = %v, want [new.txt]", rels(got))
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	root := makeTree(t, map[string]string{"a/1": "", "a/2": "", "b/1": "", "c/1": ""})
+
+	var visited []string
+	err := Walk(root, WalkOptions{}, func(e Entry) error {
+		visited = append(visited, e.Rel)
+		switch e.Rel {
+		case "a":
+			return fs.SkipDir
+		case "b/1":
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if want := []string{"a", "b", "b/1"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("Walk() visited %v, want %v", visited, want)
+	}
+
+	if _, err := Find(filepath.Join(root, "missing"), WalkOptions{}); err == nil {
+		t.Error("Find() on missing root should fail")
+	}
+}
+
+func TestWalkerSymlinkCycle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	root := makeTree(t, map[string]string{"dir/file": ""})
+	if err := os.Symlink("..", filepath.Join(root, "dir", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Find(root, WalkOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if want := []string{"dir", "dir/file", "dir/loop"}; !reflect.DeepEqual(rels(got), want) {
+		t.Errorf("Find() = %v, want %v", rels(got), want)
+	}
+	if !got[2].Symlink || !got[2].IsDir() {
+		t.Errorf("Find() loop entry = %+v, want followed symlink", got[2])
+	}
+
+	w := NewWalker(root, WalkOptions{})
+	count := 0
+	for w.Next() {
+		count++
+		if e := w.Entry(); e.Rel == "dir/loop" && e.IsDir() {
+			t.Error("symlink should not be followed by default")
+		}
+	}
+	if w.Err() != nil || count != 3 {
+		t.Errorf("Walker count = %d, err = %v", count, w.Err())
+	}
+}
@@ -0,0 +1,316 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Op is the kind of change reported by Watch.
+//
+// 文件变化的类型
+type Op int
+
+const (
+	// Create is reported when a path appears.
+	Create Op = iota
+	// Write is reported when the size, modification time, mode or (with WatchOptions.Hash) content of a path changes.
+	Write
+	// Remove is reported when a path disappears.
+	Remove
+	// Rename is reported when a path disappears and an identical one appears in the same poll.
+	Rename
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Write:
+		return "write"
+	case Remove:
+		return "remove"
+	case Rename:
+		return "rename"
+	default:
+		return fmt.Sprintf("Op(%d)", int(op))
+	}
+}
+
+// Event is a change detected by Watch.
+//
+// 文件变化事件
+type Event struct {
+	Op   Op
+	Path string
+	// OldPath is the previous path of a Rename.
+	OldPath string
+}
+
+func (e Event) String() string {
+	if e.Op == Rename {
+		return fmt.Sprintf("%s %s -> %s", e.Op, e.OldPath, e.Path)
+	}
+	return fmt.Sprintf("%s %s", e.Op, e.Path)
+}
+
+// WatchOptions configures WatchWithOptions.
+//
+// 监听的配置项
+type WatchOptions struct {
+	// Interval is the time between two polls. Defaults to one second.
+	Interval time.Duration
+	// Recursive watches the whole tree of watched directories instead of their direct children.
+	Recursive bool
+	// Include, if not empty, only reports files matching one of these globs (see MatchGlob),
+	// relative to the watched directory.
+	Include []string
+	// Exclude ignores the files and directories matching one of these globs.
+	Exclude []string
+	// Hash also compares content hashes, to catch writes that keep the size and modification time.
+	// Every watched file is read on every poll, so only use it for a handful of small files.
+	Hash bool
+	// Quiet coalesces bursts of changes: events are held back until no change has been seen for
+	// this long, and multiple changes of one path are merged into one event.
+	Quiet time.Duration
+	// OnError is called when a poll fails to read a path. Errors are ignored by default.
+	OnError func(err error)
+}
+
+type fileState struct {
+	size  int64
+	mtime int64
+	mode  os.FileMode
+	hash  string
+}
+
+// Watch polls paths every interval and sends the changes on the returned channel,
+// which is closed when ctx is done. Directories are watched recursively.
+// Paths that do not exist yet are reported once they are created.
+//
+// 轮询监听文件和目录的变化，不依赖fsnotify和系统API
+// 示例:
+//
+//	events, err := file.Watch(ctx, []string{"config.yaml"}, time.Second)
+//	for e := range events {
+//		if e.Op == file.Write { reload() }
+//	}
+func Watch(ctx context.Context, paths []string, interval time.Duration) (<-chan Event, error) {
+	return WatchWithOptions(ctx, paths, WatchOptions{Interval: interval, Recursive: true})
+}
+
+// WatchWithOptions is like Watch with more control. The initial snapshot is taken before it returns,
+// so every change made after the call is reported.
+//
+// 同Watch，可以指定更多配置
+func WatchWithOptions(ctx context.Context, paths []string, opts WatchOptions) (<-chan Event, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	w := &poller{paths: paths, opts: opts}
+	prev, err := w.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, 64)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		var (
+			pending    []Event
+			lastChange time.Time
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := w.snapshot()
+			if err != nil {
+				w.error(err)
+				continue
+			}
+			changes := diffStates(prev, cur)
+			prev = cur
+
+			if opts.Quiet <= 0 {
+				pending = changes
+			} else {
+				if len(changes) > 0 {
+					pending = mergeEvents(pending, changes)
+					lastChange = time.Now()
+				}
+				if time.Since(lastChange) < opts.Quiet {
+					continue
+				}
+			}
+			for _, e := range pending {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			pending = nil
+		}
+	}()
+	return events, nil
+}
+
+type poller struct {
+	paths []string
+	opts  WatchOptions
+}
+
+func (w *poller) error(err error) {
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
+
+func (w *poller) snapshot() (map[string]fileState, error) {
+	states := map[string]fileState{}
+	for _, root := range w.paths {
+		info, err := os.Stat(root)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		states[root] = w.state(root, info)
+		if !info.IsDir() {
+			continue
+		}
+
+		walkOpts := WalkOptions{
+			OnError: func(path string, err error) error {
+				w.error(err)
+				return nil
+			},
+		}
+		if !w.opts.Recursive {
+			walkOpts.MaxDepth = 1
+		}
+		if len(w.opts.Exclude) > 0 {
+			walkOpts.Prune = ByGlob(w.opts.Exclude...)
+			walkOpts.Filters = append(walkOpts.Filters, Not(ByGlob(w.opts.Exclude...)))
+		}
+		if len(w.opts.Include) > 0 {
+			walkOpts.Filters = append(walkOpts.Filters, Or(DirsOnly(), ByGlob(w.opts.Include...)))
+		}
+		err = Walk(root, walkOpts, func(e Entry) error {
+			states[e.Path] = w.state(e.Path, e.FileInfo)
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return states, nil
+}
+
+func (w *poller) state(path string, info os.FileInfo) fileState {
+	s := fileState{size: info.Size(), mtime: info.ModTime().UnixNano(), mode: info.Mode()}
+	if w.opts.Hash && info.Mode().IsRegular() {
+		sum, err := Hash(path, SHA256)
+		if err != nil {
+			w.error(err)
+		}
+		s.hash = sum
+	}
+	return s
+}
+
+// diffStates compares two snapshots, pairing removed and created files with the same state into renames.
+func diffStates(prev, cur map[string]fileState) []Event {
+	var created, removed, written []string
+	for path, s := range cur {
+		old, ok := prev[path]
+		switch {
+		case !ok:
+			created = append(created, path)
+		case old != s && !(s.mode.IsDir() && old.mode == s.mode):
+			// 目录的大小和修改时间随内容变化，只报告其中文件的事件
+			written = append(written, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(created)
+	sort.Strings(removed)
+	sort.Strings(written)
+
+	var events []Event
+	renamed := map[string]bool{}
+	for _, oldPath := range removed {
+		old := prev[oldPath]
+		if old.mode.IsDir() {
+			continue
+		}
+		for _, newPath := range created {
+			if !renamed[newPath] && cur[newPath] == old {
+				renamed[oldPath], renamed[newPath] = true, true
+				events = append(events, Event{Op: Rename, Path: newPath, OldPath: oldPath})
+				break
+			}
+		}
+	}
+	for _, p := range created {
+		if !renamed[p] {
+			events = append(events, Event{Op: Create, Path: p})
+		}
+	}
+	for _, p := range written {
+		events = append(events, Event{Op: Write, Path: p})
+	}
+	for _, p := range removed {
+		if !renamed[p] {
+			events = append(events, Event{Op: Remove, Path: p})
+		}
+	}
+	return events
+}
+
+// mergeEvents folds changes into pending so that each path has at most one event.
+func mergeEvents(pending, changes []Event) []Event {
+	index := make(map[string]int, len(pending))
+	for i, e := range pending {
+		index[e.Path] = i
+	}
+	for _, e := range changes {
+		i, ok := index[e.Path]
+		if !ok || e.Op == Rename || pending[i].Op == Rename {
+			index[e.Path] = len(pending)
+			pending = append(pending, e)
+			continue
+		}
+		switch prev := pending[i].Op; {
+		case prev == Create && e.Op == Write:
+		case prev == Create && e.Op == Remove:
+			pending[i].Op = -1
+		case prev == Remove && e.Op == Create:
+			pending[i].Op = Write
+		default:
+			pending[i].Op = e.Op
+		}
+	}
+
+	result := pending[:0]
+	for _, e := range pending {
+		if e.Op >= 0 {
+			result = append(result, e)
+		}
+	}
+	return result
+}
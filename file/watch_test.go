@@ -0,0 +1,225 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// collect reads the raw events until none arrives for idle.
+func collect(t *testing.T, events <-chan Event, idle time.Duration) []Event {
+	t.Helper()
+	var got []Event
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, e)
+		case <-time.After(idle):
+			return got
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.txt":     "a",
+		"b.txt":     "b",
+		"sub/c.txt": "c",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx, []string{root}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placeFile(t, filepath.Join(root, "new.txt"), "new")
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Create, Path: filepath.Join(root, "new.txt")}}) {
+		t.Errorf("create: got %v", got)
+	}
+
+	overwriteFile(t, filepath.Join(root, "sub/c.txt"), "changed")
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Write, Path: filepath.Join(root, "sub/c.txt")}}) {
+		t.Errorf("write: got %v", got)
+	}
+
+	// 不设置Quiet时，截断和之后的写入分别报告
+	if err := os.Truncate(filepath.Join(root, "sub/c.txt"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Write, Path: filepath.Join(root, "sub/c.txt")}}) {
+		t.Errorf("truncate: got %v", got)
+	}
+	overwriteFile(t, filepath.Join(root, "sub/c.txt"), "again")
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Write, Path: filepath.Join(root, "sub/c.txt")}}) {
+		t.Errorf("write after truncate: got %v", got)
+	}
+
+	if err := os.Rename(filepath.Join(root, "a.txt"), filepath.Join(root, "renamed.txt")); err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{{Op: Rename, Path: filepath.Join(root, "renamed.txt"), OldPath: filepath.Join(root, "a.txt")}}
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, want) {
+		t.Errorf("rename: got %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Remove, Path: filepath.Join(root, "b.txt")}}) {
+		t.Errorf("remove: got %v", got)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("events should be closed after cancel")
+	}
+}
+
+func TestWatchMissingPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "later.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx, []string{path}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	placeFile(t, path, "x")
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Create, Path: path}}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestWatchFilters(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"sub/":  "",
+		"skip/": "",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := WatchWithOptions(ctx, []string{root}, WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Include:  []string{"*.go"},
+		Exclude:  []string{"skip"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	placeFile(t, filepath.Join(root, "main.go"), "package main")
+	placeFile(t, filepath.Join(root, "notes.txt"), "ignored")
+	placeFile(t, filepath.Join(root, "skip/x.go"), "excluded")
+	placeFile(t, filepath.Join(root, "sub/deep.go"), "not recursive")
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Create, Path: filepath.Join(root, "main.go")}}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestWatchQuiet(t *testing.T) {
+	root := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := WatchWithOptions(ctx, []string{root}, WatchOptions{
+		Interval:  5 * time.Millisecond,
+		Recursive: true,
+		Quiet:     80 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(root, "burst.txt")
+	tmp := filepath.Join(root, "tmp.txt")
+	writeFile(t, tmp, "temporary")
+	for i := 0; i < 5; i++ {
+		writeFile(t, path, string(make([]byte, i+1)))
+		time.Sleep(15 * time.Millisecond)
+	}
+	if err := os.Remove(tmp); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, events, 300*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Create, Path: path}}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestWatchHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "same.txt")
+	writeFile(t, path, "aaaa")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := WatchWithOptions(ctx, []string{path}, WatchOptions{Interval: 10 * time.Millisecond, Hash: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同样的大小和修改时间，只有内容不同
+	tmp := path + ".new"
+	writeFile(t, tmp, "bbbb")
+	if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if got := collect(t, events, 100*time.Millisecond); !reflect.DeepEqual(got, []Event{{Op: Write, Path: path}}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	pending := []Event{{Op: Create, Path: "a"}, {Op: Remove, Path: "b"}, {Op: Create, Path: "c"}}
+	got := mergeEvents(pending, []Event{
+		{Op: Write, Path: "a"},
+		{Op: Create, Path: "b"},
+		{Op: Remove, Path: "c"},
+		{Op: Write, Path: "d"},
+	})
+	want := []Event{{Op: Create, Path: "a"}, {Op: Write, Path: "b"}, {Op: Write, Path: "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// placeFile creates path with its content in one step by renaming a file written elsewhere,
+// unlike os.WriteFile whose empty file a poll may see before the content.
+func placeFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := filepath.Join(t.TempDir(), filepath.Base(path))
+	writeFile(t, tmp, content)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// overwriteFile writes content over the start of the existing path with a single write, without truncating it first.
+func overwriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
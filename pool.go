@@ -0,0 +1,390 @@
+package goutils
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPoolClosed is returned when submitting to a pool that is shutting down,
+	// and is used to reject queued tasks that were discarded by a forced shutdown.
+	ErrPoolClosed = errors.New("pool closed")
+	// ErrQueueFull is returned by Submit when the queue is full and the policy is QueueError.
+	ErrQueueFull = errors.New("pool queue full")
+	// ErrTaskDropped rejects the future of a task that was dropped because the queue was full and the policy is QueueDrop.
+	ErrTaskDropped = errors.New("pool task dropped")
+)
+
+// QueuePolicy decides what Submit does when the pool queue is full.
+//
+// 队列满时的处理策略
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks Submit until there is room in the queue or the context is done.
+	QueueBlock QueuePolicy = iota
+	// QueueDrop drops the new task: Submit succeeds but the returned future is rejected with ErrTaskDropped.
+	QueueDrop
+	// QueueError makes Submit return ErrQueueFull.
+	QueueError
+)
+
+// PoolOptions configures a Pool. The zero value is usable: one worker, a queue of one task and blocking submission.
+//
+// Pool的配置项
+type PoolOptions[In any, Out any] struct {
+	// Workers is the number of workers that are always running. Defaults to 1.
+	Workers int
+	// MaxWorkers enables auto scaling when greater than Workers: extra workers are started when
+	// all workers are busy, and stop again after being idle for IdleTimeout.
+	MaxWorkers int
+	// IdleTimeout is how long an extra worker waits for a task before stopping. Defaults to one minute.
+	IdleTimeout time.Duration
+	// QueueSize is the maximum number of tasks waiting for a worker. Defaults to MaxWorkers.
+	QueueSize int
+	// Policy is applied when the queue is full.
+	Policy QueuePolicy
+	// TaskTimeout, if positive, bounds the context passed to each task.
+	TaskTimeout time.Duration
+	// OnResult, if set, is called by the worker after each task finishes.
+	OnResult func(in In, out Out, err error)
+}
+
+// PoolMetrics is a snapshot of the pool counters.
+//
+// Pool的运行统计
+type PoolMetrics struct {
+	Workers   int
+	Queued    int
+	Running   int
+	Completed int64
+	Failed    int64
+	Dropped   int64
+}
+
+// Pool runs tasks on a bounded set of workers, feeding them from a bounded priority queue.
+// Tasks with a higher priority run first; tasks with the same priority run in submission order.
+//
+// 泛型工作池，支持有界队列、优先级、自动扩缩容和优雅关闭
+type Pool[In any, Out any] struct {
+	handler func(ctx context.Context, in In) (Out, error)
+	opts    PoolOptions[In, Out]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	slots   chan struct{}
+	ready   chan struct{}
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	queue   poolQueue[In, Out]
+	seq     uint64
+	closed  bool
+	workers int
+	idle    int
+	running int
+
+	completed int64
+	failed    int64
+	dropped   int64
+}
+
+// NewPool creates a Pool that processes submitted values with handler and starts its workers.
+//
+// 创建工作池并启动worker
+// 示例:
+//
+//	p := NewPool(func(ctx context.Context, url string) (int, error) { return fetch(ctx, url) }, PoolOptions[string, int]{Workers: 4})
+//	f, _ := p.Submit(ctx, "https://example.com")
+//	status, err := f.Await(ctx)
+func NewPool[In any, Out any](handler func(ctx context.Context, in In) (Out, error), opts PoolOptions[In, Out]) *Pool[In, Out] {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxWorkers < opts.Workers {
+		opts.MaxWorkers = opts.Workers
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = time.Minute
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.MaxWorkers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool[In, Out]{
+		handler: handler,
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		slots:   make(chan struct{}, opts.QueueSize),
+		ready:   make(chan struct{}, opts.QueueSize),
+		closing: make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	for i := 0; i < opts.Workers; i++ {
+		p.spawn()
+	}
+	p.mu.Unlock()
+
+	return p
+}
+
+// Submit queues in with priority 0. See SubmitPriority.
+//
+// 提交任务
+func (p *Pool[In, Out]) Submit(ctx context.Context, in In) (*Future[Out], error) {
+	return p.SubmitPriority(ctx, in, 0)
+}
+
+// SubmitPriority queues in and returns a Future for its result. Higher priorities are processed first.
+// When the queue is full the pool's QueuePolicy applies; with QueueBlock, ctx bounds the wait.
+//
+// 按优先级提交任务，priority越大越先执行
+func (p *Pool[In, Out]) SubmitPriority(ctx context.Context, in In, priority int) (*Future[Out], error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		switch p.opts.Policy {
+		case QueueDrop:
+			p.mu.Lock()
+			p.dropped++
+			p.mu.Unlock()
+			return Reject[Out](ErrTaskDropped), nil
+		case QueueError:
+			return nil, ErrQueueFull
+		default:
+			select {
+			case p.slots <- struct{}{}:
+			case <-p.closing:
+				return nil, ErrPoolClosed
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		<-p.slots
+		return nil, ErrPoolClosed
+	}
+
+	t := &poolTask[In, Out]{in: in, priority: priority, seq: p.seq, future: newFuture[Out]()}
+	p.seq++
+	heap.Push(&p.queue, t)
+	p.ready <- struct{}{}
+
+	if p.idle == 0 && p.workers < p.opts.MaxWorkers {
+		p.spawn()
+	}
+	return t.future, nil
+}
+
+// Metrics returns a snapshot of the pool counters.
+//
+// 返回运行统计
+func (p *Pool[In, Out]) Metrics() PoolMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolMetrics{
+		Workers:   p.workers,
+		Queued:    p.queue.Len(),
+		Running:   p.running,
+		Completed: p.completed,
+		Failed:    p.failed,
+		Dropped:   p.dropped,
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the queued and running tasks to finish.
+// If ctx is done first, the context of in-flight tasks is cancelled, queued tasks are rejected
+// with ErrPoolClosed, and ctx.Err() is returned once the workers have stopped.
+//
+// 优雅关闭：停止接收新任务，等待队列中的任务执行完毕；ctx结束时取消执行中的任务并丢弃队列
+func (p *Pool[In, Out]) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	for p.queue.Len() > 0 {
+		t := heap.Pop(&p.queue).(*poolTask[In, Out])
+		t.future.settle(Empty[Out](), ErrPoolClosed)
+		<-p.slots
+	}
+	p.mu.Unlock()
+	p.cancel()
+	<-done
+	return ctx.Err()
+}
+
+func (p *Pool[In, Out]) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// spawn starts a worker, p.mu must be held.
+func (p *Pool[In, Out]) spawn() {
+	p.workers++
+	p.wg.Add(1)
+	go p.work()
+}
+
+func (p *Pool[In, Out]) work() {
+	defer p.wg.Done()
+
+	for {
+		var (
+			idleTimer *time.Timer
+			idleC     <-chan time.Time
+		)
+		p.mu.Lock()
+		p.idle++
+		if p.workers > p.opts.Workers {
+			idleTimer = time.NewTimer(p.opts.IdleTimeout)
+			idleC = idleTimer.C
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-p.ready:
+			if idleTimer != nil {
+				idleTimer.Stop()
+			}
+			p.mu.Lock()
+			p.idle--
+			if p.queue.Len() == 0 {
+				p.mu.Unlock()
+				continue
+			}
+			t := heap.Pop(&p.queue).(*poolTask[In, Out])
+			p.running++
+			p.mu.Unlock()
+			<-p.slots
+			p.run(t)
+		case <-idleC:
+			p.mu.Lock()
+			p.idle--
+			if p.workers > p.opts.Workers {
+				p.workers--
+				p.mu.Unlock()
+				return
+			}
+			p.mu.Unlock()
+		case <-p.closing:
+			if idleTimer != nil {
+				idleTimer.Stop()
+			}
+			p.mu.Lock()
+			p.idle--
+			if p.queue.Len() == 0 {
+				p.workers--
+				p.mu.Unlock()
+				return
+			}
+			// closing关闭后一直就绪，直接取出排队的任务执行，不能回到select空转等待ready
+			t := heap.Pop(&p.queue).(*poolTask[In, Out])
+			p.running++
+			p.mu.Unlock()
+			<-p.slots
+			p.run(t)
+		}
+	}
+}
+
+func (p *Pool[In, Out]) run(t *poolTask[In, Out]) {
+	ctx := p.ctx
+	if p.opts.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.TaskTimeout)
+		defer cancel()
+	}
+
+	out, err := p.call(ctx, t.in)
+
+	p.mu.Lock()
+	p.running--
+	if err != nil {
+		p.failed++
+	} else {
+		p.completed++
+	}
+	p.mu.Unlock()
+
+	if p.opts.OnResult != nil {
+		p.opts.OnResult(t.in, out, err)
+	}
+	t.future.settle(out, err)
+}
+
+func (p *Pool[In, Out]) call(ctx context.Context, in In) (out Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = Empty[Out]()
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return p.handler(ctx, in)
+}
+
+type poolTask[In any, Out any] struct {
+	in       In
+	priority int
+	seq      uint64
+	future   *Future[Out]
+}
+
+// poolQueue implements heap.Interface, ordering by priority then submission order.
+type poolQueue[In any, Out any] []*poolTask[In, Out]
+
+func (q poolQueue[In, Out]) Len() int { return len(q) }
+
+func (q poolQueue[In, Out]) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q poolQueue[In, Out]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *poolQueue[In, Out]) Push(x any) { *q = append(*q, x.(*poolTask[In, Out])) }
+
+func (q *poolQueue[In, Out]) Pop() any {
+	old := *q
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return t
+}
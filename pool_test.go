@@ -0,0 +1,233 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolSubmit(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	errOdd := errors.New("odd")
+	var mu sync.Mutex
+	results := map[int]int{}
+	p := NewPool(func(_ context.Context, in int) (int, error) {
+		if in%2 == 1 {
+			return 0, errOdd
+		}
+		return in * 10, nil
+	}, PoolOptions[int, int]{
+		Workers:   3,
+		QueueSize: 10,
+		OnResult: func(in int, out int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			results[in] = out
+		},
+	})
+
+	futures := make([]*Future[int], 0, 6)
+	for i := 0; i < 6; i++ {
+		f, err := p.Submit(ctx, i)
+		is.NoError(err)
+		futures = append(futures, f)
+	}
+	settled, err := AllSettled(ctx, futures...)
+	is.NoError(err)
+	for i, s := range settled {
+		if i%2 == 1 {
+			is.ErrorIs(s.Err, errOdd)
+		} else {
+			is.Equal(i*10, s.Value)
+		}
+	}
+
+	is.NoError(p.Shutdown(ctx))
+	m := p.Metrics()
+	is.Equal(int64(3), m.Completed)
+	is.Equal(int64(3), m.Failed)
+	is.Equal(0, m.Workers)
+	is.Len(results, 6)
+
+	_, err = p.Submit(ctx, 1)
+	is.ErrorIs(err, ErrPoolClosed)
+}
+
+func TestPoolPriority(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	var order []int
+	p := NewPool(func(_ context.Context, in int) (int, error) {
+		if in < 0 {
+			<-release
+			return in, nil
+		}
+		order = append(order, in)
+		return in, nil
+	}, PoolOptions[int, int]{QueueSize: 10})
+
+	// 阻塞唯一的worker，让后续任务在队列中排序
+	blocker, _ := p.Submit(ctx, -1)
+	for p.Metrics().Running == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, _ = p.SubmitPriority(ctx, 1, 1)
+	_, _ = p.SubmitPriority(ctx, 2, 5)
+	_, _ = p.SubmitPriority(ctx, 3, 1)
+	_, _ = p.SubmitPriority(ctx, 4, 0)
+	close(release)
+	_, _ = blocker.Result()
+
+	is.NoError(p.Shutdown(ctx))
+	is.Equal([]int{2, 1, 3, 4}, order)
+}
+
+func TestPoolQueuePolicy(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	for _, policy := range []QueuePolicy{QueueBlock, QueueDrop, QueueError} {
+		release := make(chan struct{})
+		p := NewPool(func(_ context.Context, in int) (int, error) {
+			<-release
+			return in, nil
+		}, PoolOptions[int, int]{QueueSize: 1, Policy: policy})
+
+		_, _ = p.Submit(ctx, 1)
+		for p.Metrics().Running == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		_, err := p.Submit(ctx, 2)
+		is.NoError(err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		f, err := p.Submit(timeoutCtx, 3)
+		cancel()
+		switch policy {
+		case QueueBlock:
+			is.ErrorIs(err, context.DeadlineExceeded)
+		case QueueDrop:
+			is.NoError(err)
+			_, err = f.Result()
+			is.ErrorIs(err, ErrTaskDropped)
+			is.Equal(int64(1), p.Metrics().Dropped)
+		case QueueError:
+			is.ErrorIs(err, ErrQueueFull)
+		}
+
+		close(release)
+		is.NoError(p.Shutdown(ctx))
+	}
+}
+
+func TestPoolTimeoutAndPanic(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	p := NewPool(func(ctx context.Context, in int) (int, error) {
+		if in == 0 {
+			panic("zero")
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, PoolOptions[int, int]{Workers: 2, TaskTimeout: 10 * time.Millisecond})
+
+	f1, _ := p.Submit(ctx, 0)
+	f2, _ := p.Submit(ctx, 1)
+
+	_, err1 := f1.Result()
+	var panicErr *PanicError
+	is.ErrorAs(err1, &panicErr)
+	_, err2 := f2.Result()
+	is.ErrorIs(err2, context.DeadlineExceeded)
+	is.NoError(p.Shutdown(ctx))
+}
+
+func TestPoolAutoScale(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	p := NewPool(func(_ context.Context, in int) (int, error) {
+		<-release
+		return in, nil
+	}, PoolOptions[int, int]{Workers: 1, MaxWorkers: 3, QueueSize: 3, IdleTimeout: 10 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		_, _ = p.Submit(ctx, i)
+	}
+	is.Eventually(func() bool { return p.Metrics().Running == 3 }, time.Second, time.Millisecond)
+	is.Equal(3, p.Metrics().Workers)
+
+	close(release)
+	is.Eventually(func() bool { return p.Metrics().Workers == 1 }, time.Second, time.Millisecond)
+	is.NoError(p.Shutdown(ctx))
+}
+
+func TestPoolShutdownCancel(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	p := NewPool(func(ctx context.Context, in int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, PoolOptions[int, int]{QueueSize: 2})
+
+	running, _ := p.Submit(ctx, 1)
+	queued, _ := p.Submit(ctx, 2)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	is.ErrorIs(p.Shutdown(timeoutCtx), context.DeadlineExceeded)
+
+	_, err := running.Result()
+	is.ErrorIs(err, context.Canceled)
+	_, err = queued.Result()
+	is.ErrorIs(err, ErrPoolClosed)
+}
+
+func TestPoolShutdownDrain(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+	ctx := context.Background()
+
+	gate := make(chan struct{})
+	p := NewPool(func(ctx context.Context, in int) (int, error) {
+		<-gate
+		return in * 2, nil
+	}, PoolOptions[int, int]{QueueSize: 4})
+
+	var futures []*Future[int]
+	for i := 1; i <= 4; i++ {
+		f, err := p.Submit(ctx, i)
+		is.NoError(err)
+		futures = append(futures, f)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Shutdown(ctx) }()
+	// 关闭期间排队的任务仍然执行
+	time.Sleep(20 * time.Millisecond)
+	close(gate)
+	is.NoError(<-done)
+	for i, f := range futures {
+		v, err := f.Result()
+		is.NoError(err)
+		is.Equal((i+1)*2, v)
+	}
+	is.Equal(int64(4), p.Metrics().Completed)
+}
@@ -0,0 +1,441 @@
+package goutils
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrExceedsLimit is returned when a request asks for more permits than the limiter can ever grant at once.
+//
+// 一次请求的数量超过了限流器的容量
+var ErrExceedsLimit = errors.New("rate limit: n exceeds limiter capacity")
+
+// Limiter is implemented by the in-process rate limiters.
+//
+// 限流器接口
+type Limiter interface {
+	// Allow reports whether one event may happen now, consuming a permit if so.
+	Allow() bool
+	// AllowN reports whether n events may happen now, consuming n permits if so.
+	AllowN(n int) bool
+	// Wait blocks until one event may happen or ctx is done.
+	Wait(ctx context.Context) error
+	// WaitN blocks until n events may happen or ctx is done.
+	WaitN(ctx context.Context, n int) error
+}
+
+// TokenBucket is a token bucket rate limiter: it is refilled at rate tokens per second
+// and holds at most burst tokens.
+//
+// 令牌桶限流器
+type TokenBucket struct {
+	mu     sync.Mutex
+	clock  Clock
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full TokenBucket that refills rate tokens per second up to burst tokens.
+//
+// 创建令牌桶，rate为每秒生成的令牌数，burst为桶容量
+// 示例:
+//
+//	l := NewTokenBucket(10, 5) // 每秒10个请求，最多突发5个
+//	if l.Allow() { ... }
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return NewTokenBucketWithClock(rate, burst, SystemClock)
+}
+
+// NewTokenBucketWithClock is like NewTokenBucket but reads time from clock.
+//
+// 同NewTokenBucket，使用指定的Clock
+func NewTokenBucketWithClock(rate float64, burst int, clock Clock) *TokenBucket {
+	return &TokenBucket{
+		clock:  clock,
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// Tokens returns the number of tokens currently available.
+//
+// 返回当前可用的令牌数
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.clock.Now())
+	return b.tokens
+}
+
+// Allow is shorthand for AllowN(1).
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN consumes n tokens and returns true if they are all available now, otherwise it consumes nothing.
+//
+// 立即获取n个令牌，不足时返回false
+func (b *TokenBucket) AllowN(n int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(b.clock.Now())
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// Reserve is shorthand for ReserveN(1).
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(1)
+}
+
+// ReserveN takes n tokens now, possibly going into debt, and returns a Reservation telling
+// how long the caller must wait before acting. The reservation is not OK if n exceeds the burst.
+//
+// 预定n个令牌，返回需要等待的时间
+func (b *TokenBucket) ReserveN(n int) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if n > b.burst {
+		return &Reservation{limiter: b, timeToAct: now}
+	}
+
+	b.refill(now)
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = b.durationFor(-b.tokens)
+	}
+	return &Reservation{ok: true, limiter: b, tokens: n, timeToAct: now.Add(delay)}
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	return b.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n tokens are available or ctx is done. If the wait would outlast
+// the ctx deadline it returns immediately without consuming tokens.
+//
+// 阻塞等待n个令牌
+func (b *TokenBucket) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := b.ReserveN(n)
+	if !r.OK() {
+		return ErrExceedsLimit
+	}
+	delay := r.Delay()
+	if delay <= 0 {
+		return nil
+	}
+	// ctx的截止时间是真实时间，与等待时长比较，而不是与注入Clock上的时间比较
+	if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+		r.Cancel()
+		return context.DeadlineExceeded
+	}
+
+	select {
+	case <-b.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+	b.tokens = math.Min(float64(b.burst), b.tokens+elapsed.Seconds()*b.rate)
+}
+
+func (b *TokenBucket) durationFor(tokens float64) time.Duration {
+	if b.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(math.Ceil(tokens / b.rate * float64(time.Second)))
+}
+
+// Reservation holds tokens taken by TokenBucket.ReserveN.
+//
+// 令牌预定信息
+type Reservation struct {
+	ok        bool
+	limiter   *TokenBucket
+	tokens    int
+	timeToAct time.Time
+	cancelled bool
+}
+
+// OK reports whether the reservation can ever be honoured.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the caller must wait before acting on the reservation.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	delay := r.timeToAct.Sub(r.limiter.clock.Now())
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Cancel gives the reserved tokens back to the bucket, if the reservation has not been acted on yet.
+//
+// 取消预定，归还令牌
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	b := r.limiter
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if r.cancelled || !r.timeToAct.After(now) {
+		return
+	}
+	r.cancelled = true
+	b.refill(now)
+	b.tokens = math.Min(float64(b.burst), b.tokens+float64(r.tokens))
+}
+
+// SlidingWindow is a sliding window counter rate limiter allowing at most limit events per window.
+// The count is estimated from the current fixed window plus the previous one weighted by how much
+// of it still overlaps the sliding window.
+//
+// 滑动窗口计数限流器
+type SlidingWindow struct {
+	mu          sync.Mutex
+	clock       Clock
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	prev        int
+	curr        int
+}
+
+// NewSlidingWindow returns a SlidingWindow allowing limit events per window.
+//
+// 创建滑动窗口限流器，每个window时间内最多limit次
+// 示例:
+//
+//	l := NewSlidingWindow(100, time.Minute) // 每分钟最多100次
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	return NewSlidingWindowWithClock(limit, window, SystemClock)
+}
+
+// NewSlidingWindowWithClock is like NewSlidingWindow but reads time from clock.
+//
+// 同NewSlidingWindow，使用指定的Clock
+func NewSlidingWindowWithClock(limit int, window time.Duration, clock Clock) *SlidingWindow {
+	return &SlidingWindow{
+		clock:       clock,
+		limit:       limit,
+		window:      window,
+		windowStart: clock.Now().Truncate(window),
+	}
+}
+
+// Allow is shorthand for AllowN(1).
+func (w *SlidingWindow) Allow() bool {
+	return w.AllowN(1)
+}
+
+// AllowN records n events and returns true if they fit in the window, otherwise it records nothing.
+//
+// 立即记录n次请求，超出限制时返回false
+func (w *SlidingWindow) AllowN(n int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ok, _ := w.take(n)
+	return ok
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	return w.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events fit in the window or ctx is done.
+//
+// 阻塞等待直到可以记录n次请求
+func (w *SlidingWindow) WaitN(ctx context.Context, n int) error {
+	if n > w.limit {
+		return ErrExceedsLimit
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		ok, delay := w.take(n)
+		w.mu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-w.clock.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// take records n events if they fit, otherwise it returns how long to wait before retrying.
+// w.mu must be held.
+func (w *SlidingWindow) take(n int) (bool, time.Duration) {
+	now := w.clock.Now()
+	w.advance(now)
+
+	elapsed := now.Sub(w.windowStart)
+	weight := 1 - float64(elapsed)/float64(w.window)
+	count := float64(w.prev)*weight + float64(w.curr)
+	if count+float64(n) <= float64(w.limit) {
+		w.curr += n
+		return true, 0
+	}
+
+	// 直到下一个窗口开始前，只有上一个窗口的权重在衰减
+	untilNext := w.windowStart.Add(w.window).Sub(now)
+	room := float64(w.limit - w.curr - n)
+	if room < 0 || w.prev == 0 {
+		return false, untilNext
+	}
+	need := time.Duration(math.Ceil((1 - room/float64(w.prev)) * float64(w.window)))
+	delay := need - elapsed
+	if delay <= 0 {
+		delay = time.Nanosecond
+	}
+	return false, min(delay, untilNext)
+}
+
+func (w *SlidingWindow) advance(now time.Time) {
+	start := now.Truncate(w.window)
+	switch {
+	case start.Equal(w.windowStart):
+	case start.Equal(w.windowStart.Add(w.window)):
+		w.prev, w.curr = w.curr, 0
+		w.windowStart = start
+	case start.After(w.windowStart):
+		w.prev, w.curr = 0, 0
+		w.windowStart = start
+	}
+}
+
+// KeyedLimiter keeps one Limiter per key, creating them lazily and evicting the ones
+// that have not been used for the idle timeout. It is typically used for per-user or per-IP limits.
+//
+// 按key区分的限流器，按需创建，并淘汰长时间未使用的限流器
+type KeyedLimiter[K comparable] struct {
+	mu        sync.Mutex
+	clock     Clock
+	factory   func(key K) Limiter
+	idle      time.Duration
+	limiters  map[K]*keyedEntry
+	lastSweep time.Time
+}
+
+type keyedEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+// NewKeyedLimiter returns a KeyedLimiter that creates limiters with factory and evicts them after idle without use.
+// A non-positive idle disables eviction.
+//
+// 创建按key区分的限流器
+// 示例:
+//
+//	l := NewKeyedLimiter(func(ip string) Limiter { return NewTokenBucket(1, 5) }, 10*time.Minute)
+//	if !l.Allow(clientIP) { ... }
+func NewKeyedLimiter[K comparable](factory func(key K) Limiter, idle time.Duration) *KeyedLimiter[K] {
+	return NewKeyedLimiterWithClock(factory, idle, SystemClock)
+}
+
+// NewKeyedLimiterWithClock is like NewKeyedLimiter but reads time from clock.
+//
+// 同NewKeyedLimiter，使用指定的Clock
+func NewKeyedLimiterWithClock[K comparable](factory func(key K) Limiter, idle time.Duration, clock Clock) *KeyedLimiter[K] {
+	return &KeyedLimiter[K]{
+		clock:     clock,
+		factory:   factory,
+		idle:      idle,
+		limiters:  map[K]*keyedEntry{},
+		lastSweep: clock.Now(),
+	}
+}
+
+// Get returns the limiter of key, creating it if needed.
+//
+// 获取key对应的限流器
+func (l *KeyedLimiter[K]) Get(key K) Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	l.sweep(now)
+
+	e, ok := l.limiters[key]
+	if !ok {
+		e = &keyedEntry{limiter: l.factory(key)}
+		l.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
+
+// Allow reports whether one event for key may happen now.
+func (l *KeyedLimiter[K]) Allow(key K) bool {
+	return l.Get(key).Allow()
+}
+
+// AllowN reports whether n events for key may happen now.
+func (l *KeyedLimiter[K]) AllowN(key K, n int) bool {
+	return l.Get(key).AllowN(n)
+}
+
+// Wait blocks until one event for key may happen or ctx is done.
+func (l *KeyedLimiter[K]) Wait(ctx context.Context, key K) error {
+	return l.Get(key).Wait(ctx)
+}
+
+// Len returns the number of limiters currently kept.
+//
+// 返回当前保存的限流器数量
+func (l *KeyedLimiter[K]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(l.clock.Now())
+	return len(l.limiters)
+}
+
+// sweep evicts idle limiters, at most once per idle period. l.mu must be held.
+func (l *KeyedLimiter[K]) sweep(now time.Time) {
+	if l.idle <= 0 || now.Sub(l.lastSweep) < l.idle {
+		return
+	}
+	l.lastSweep = now
+	for k, e := range l.limiters {
+		if now.Sub(e.lastUsed) >= l.idle {
+			delete(l.limiters, k)
+		}
+	}
+}
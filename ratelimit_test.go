@@ -0,0 +1,172 @@
+package goutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewTokenBucketWithClock(2, 3, clock)
+
+	is.True(b.AllowN(3))
+	is.False(b.Allow())
+
+	clock.Advance(500 * time.Millisecond)
+	is.True(b.Allow())
+	is.False(b.Allow())
+
+	clock.Advance(time.Hour)
+	is.InDelta(3, b.Tokens(), 0.0001)
+	is.False(b.AllowN(4))
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewTokenBucketWithClock(1, 1, clock)
+
+	r1 := b.Reserve()
+	is.True(r1.OK())
+	is.Equal(time.Duration(0), r1.Delay())
+
+	r2 := b.Reserve()
+	is.True(r2.OK())
+	is.Equal(time.Second, r2.Delay())
+
+	r2.Cancel()
+	is.InDelta(0, b.Tokens(), 0.0001)
+
+	is.False(b.ReserveN(2).OK())
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := NewTokenBucketWithClock(1, 1, clock)
+	ctx := context.Background()
+
+	is.NoError(b.Wait(ctx))
+
+	done := make(chan error)
+	go func() { done <- b.Wait(ctx) }()
+	is.Eventually(func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+	is.NoError(<-done)
+
+	is.ErrorIs(b.WaitN(ctx, 2), ErrExceedsLimit)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go func() { done <- b.Wait(cancelCtx) }()
+	is.Eventually(func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	is.ErrorIs(<-done, context.Canceled)
+	is.InDelta(0, b.Tokens(), 0.0001)
+}
+
+func TestTokenBucketWaitDeadline(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	// FakeClock的时间与真实时间无关，截止时间按剩余的真实时间判断
+	for _, start := range []time.Time{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)} {
+		clock := NewFakeClock(start)
+		b := NewTokenBucketWithClock(1, 1, clock)
+		is.True(b.Allow())
+
+		short, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		is.ErrorIs(b.Wait(short), context.DeadlineExceeded)
+		cancel()
+		is.InDelta(0, b.Tokens(), 0.0001)
+
+		long, cancel := context.WithTimeout(context.Background(), time.Hour)
+		done := make(chan error)
+		go func() { done <- b.Wait(long) }()
+		is.Eventually(func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+		clock.Advance(time.Second)
+		is.NoError(<-done)
+		cancel()
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewSlidingWindowWithClock(10, time.Minute, clock)
+
+	is.True(w.AllowN(10))
+	is.False(w.Allow())
+
+	// 新窗口开始时，上一个窗口的计数仍然全部生效
+	clock.Advance(time.Minute)
+	is.False(w.Allow())
+
+	// 过了半个窗口，上一个窗口的计数按一半计算
+	clock.Advance(30 * time.Second)
+	is.True(w.AllowN(5))
+	is.False(w.Allow())
+
+	clock.Advance(2 * time.Minute)
+	is.True(w.AllowN(10))
+}
+
+func TestSlidingWindowWait(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := NewSlidingWindowWithClock(2, time.Second, clock)
+	ctx := context.Background()
+
+	is.True(w.AllowN(2))
+	done := make(chan error)
+	go func() { done <- w.Wait(ctx) }()
+	is.Eventually(func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+	is.Eventually(func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(500 * time.Millisecond)
+	is.NoError(<-done)
+
+	is.ErrorIs(w.WaitN(ctx, 3), ErrExceedsLimit)
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	t.Parallel()
+	is := assert.New(t)
+
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	created := 0
+	l := NewKeyedLimiterWithClock(func(key string) Limiter {
+		created++
+		return NewTokenBucketWithClock(1, 1, clock)
+	}, time.Minute, clock)
+
+	is.True(l.Allow("a"))
+	is.False(l.Allow("a"))
+	is.True(l.Allow("b"))
+	is.Equal(2, l.Len())
+	is.Equal(2, created)
+
+	clock.Advance(30 * time.Second)
+	is.True(l.Allow("a"))
+
+	clock.Advance(45 * time.Second)
+	is.Equal(1, l.Len())
+
+	clock.Advance(2 * time.Minute)
+	is.Equal(0, l.Len())
+	is.True(l.Allow("b"))
+	is.Equal(3, created)
+}